@@ -0,0 +1,98 @@
+// Package audit carries who is making a request, and through which endpoint,
+// down to the repository that records every change to a volunteer, and
+// computes the field-level changes recorded.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	"github.com/Reskill-2022/volunteering/model"
+)
+
+// Actor roles besides the admin roles.
+const (
+	RoleVolunteer = "volunteer"
+	RoleSystem    = "system"
+)
+
+// Actor is whoever changes a record: an admin key holder, a signed-in
+// volunteer, or a command such as import.
+type Actor struct {
+	Name string
+	Role string
+}
+
+type (
+	actorKey    struct{}
+	endpointKey struct{}
+)
+
+// WithActor returns a copy of ctx that carries actor.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor carried by ctx, the system if none.
+func ActorFrom(ctx context.Context) Actor {
+	if actor, ok := ctx.Value(actorKey{}).(Actor); ok {
+		return actor
+	}
+	return Actor{Role: RoleSystem}
+}
+
+// WithEndpoint returns a copy of ctx that carries the API endpoint being
+// served, such as "PATCH /volunteering/admin/users/:email".
+func WithEndpoint(ctx context.Context, endpoint string) context.Context {
+	return context.WithValue(ctx, endpointKey{}, endpoint)
+}
+
+// EndpointFrom returns the endpoint carried by ctx, empty outside requests.
+func EndpointFrom(ctx context.Context) string {
+	endpoint, _ := ctx.Value(endpointKey{}).(string)
+	return endpoint
+}
+
+// Diff returns the fields that differ between before and after, named and
+// valued as they are in the API, sorted by field.
+func Diff(before, after model.User) ([]model.Change, error) {
+	b, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, name)
+	}
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []model.Change
+	for _, name := range names {
+		if !reflect.DeepEqual(b[name], a[name]) {
+			changes = append(changes, model.Change{Field: name, Before: b[name], After: a[name]})
+		}
+	}
+	return changes, nil
+}
+
+func fields(user model.User) (map[string]interface{}, error) {
+	raw, err := json.Marshal(user)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	err = json.Unmarshal(raw, &m)
+	return m, err
+}
@@ -0,0 +1,41 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Reskill-2022/volunteering/model"
+)
+
+func TestDiff(t *testing.T) {
+	before := model.User{Email: "jane@example.com", State: "Texas", VolunteerAreas: model.StringList{"Mentoring"}}
+	after := before
+	after.State = "Ohio"
+	after.VolunteerAreas = model.StringList{"Mentoring", "Resume Review"}
+	after.Answers = map[string]interface{}{"state": "Ohio"}
+
+	changes, err := Diff(before, after)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "[{answers <nil> map[state:Ohio]} {state Texas Ohio} {volunteer_areas [Mentoring] [Mentoring Resume Review]}]"
+	if got := fmt.Sprint(changes); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	if changes, _ := Diff(after, after); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+}
+
+func TestActorFrom(t *testing.T) {
+	if actor := ActorFrom(context.Background()); actor.Role != RoleSystem {
+		t.Errorf("expected the system by default, got %+v", actor)
+	}
+
+	ctx := WithActor(context.Background(), Actor{Name: "ops", Role: "admin"})
+	if actor := ActorFrom(ctx); actor.Name != "ops" || EndpointFrom(ctx) != "" {
+		t.Errorf("unexpected actor %+v", actor)
+	}
+}
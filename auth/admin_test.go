@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/Reskill-2022/volunteering/config"
+)
+
+func TestAPIKeys(t *testing.T) {
+	keys := NewAPIKeys([]config.APIKey{
+		{Name: "dashboard", Role: config.AdminViewer, Key: "viewer-key-viewer-key-viewer-key"},
+		{Name: "coordinators", Role: config.AdminCoordinator, Key: "coordinator-key-coordinator-key"},
+		{Name: "ops", Role: config.AdminAdmin, Key: "admin-key-admin-key-admin-key"},
+	})
+
+	e := echo.New()
+	handler := func(c echo.Context) error {
+		admin, _ := AdminFrom(c)
+		return c.String(http.StatusOK, admin.Name)
+	}
+	admin := e.Group("/admin", keys.Middleware())
+	admin.GET("/users", handler, RequireRole(config.AdminViewer))
+	admin.PATCH("/users", handler, RequireRole(config.AdminCoordinator))
+	admin.DELETE("/users", handler, RequireRole(config.AdminAdmin))
+
+	tests := []struct {
+		name   string
+		method string
+		key    string
+		code   int
+	}{
+		{"no key", http.MethodGet, "", http.StatusUnauthorized},
+		{"unknown key", http.MethodGet, "not-a-key", http.StatusUnauthorized},
+		{"viewer reads", http.MethodGet, "viewer-key-viewer-key-viewer-key", http.StatusOK},
+		{"viewer edits", http.MethodPatch, "viewer-key-viewer-key-viewer-key", http.StatusForbidden},
+		{"coordinator edits", http.MethodPatch, "coordinator-key-coordinator-key", http.StatusOK},
+		{"coordinator deletes", http.MethodDelete, "coordinator-key-coordinator-key", http.StatusForbidden},
+		{"admin deletes", http.MethodDelete, "admin-key-admin-key-admin-key", http.StatusOK},
+		{"admin reads", http.MethodGet, "admin-key-admin-key-admin-key", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/admin/users", nil)
+			if tt.key != "" {
+				req.Header.Set(HeaderAPIKey, tt.key)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.code {
+				t.Errorf("got status %d, want %d: %s", rec.Code, tt.code, rec.Body.String())
+			}
+		})
+	}
+}
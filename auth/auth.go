@@ -0,0 +1,137 @@
+// Package auth issues and verifies the session tokens volunteers use to access
+// their own records after signing in.
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+
+	"github.com/Reskill-2022/volunteering/audit"
+)
+
+const (
+	issuer = "volunteering"
+
+	// sessionEmailKey is the echo.Context key holding the verified email.
+	sessionEmailKey = "session_email"
+)
+
+type (
+	// TokenIssuer issues a session token for a verified email address.
+	TokenIssuer interface {
+		Issue(email string) (string, time.Time, error)
+	}
+
+	// Sessions issues and verifies HMAC-signed JWT session tokens.
+	Sessions struct {
+		secret []byte
+		ttl    time.Duration
+		now    func() time.Time
+	}
+
+	claims struct {
+		jwt.StandardClaims
+	}
+)
+
+var _ TokenIssuer = (*Sessions)(nil)
+
+func New(secret []byte, ttl time.Duration) *Sessions {
+	return &Sessions{
+		secret: secret,
+		ttl:    ttl,
+		now:    time.Now,
+	}
+}
+
+// Issue returns a token for email and the time it expires.
+func (s *Sessions) Issue(email string) (string, time.Time, error) {
+	now := s.now()
+	expiresAt := now.Add(s.ttl)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    issuer,
+			Subject:   email,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: expiresAt.Unix(),
+		},
+	})
+
+	signed, err := token.SignedString(s.secret)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign session token: %w", err)
+	}
+	return signed, expiresAt, nil
+}
+
+// Verify checks the signature and expiry of token and returns its email.
+func (s *Sessions) Verify(token string) (string, error) {
+	c := &claims{}
+	parser := &jwt.Parser{ValidMethods: []string{jwt.SigningMethodHS256.Alg()}}
+
+	_, err := parser.ParseWithClaims(token, c, func(*jwt.Token) (interface{}, error) {
+		return s.secret, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if !c.VerifyIssuer(issuer, true) || c.Subject == "" {
+		return "", fmt.Errorf("invalid session token")
+	}
+	return c.Subject, nil
+}
+
+// Middleware rejects requests without a valid "Authorization: Bearer" session
+// token and makes the token's email available through SessionEmail.
+func (s *Sessions) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := c.Request().Header.Get(echo.HeaderAuthorization)
+			token := strings.TrimPrefix(header, "Bearer ")
+			if header == "" || token == header {
+				return unauthorized(c, http.StatusUnauthorized, "Sign In Required")
+			}
+
+			email, err := s.Verify(token)
+			if err != nil {
+				return unauthorized(c, http.StatusUnauthorized, "Session Expired or Invalid. Please Sign In Again")
+			}
+
+			c.Set(sessionEmailKey, email)
+			ctx := audit.WithActor(c.Request().Context(), audit.Actor{Name: email, Role: audit.RoleVolunteer})
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+// RequireSelf only lets a session access the record whose email is in the
+// route parameter param. It must run after Middleware.
+func RequireSelf(param string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !strings.EqualFold(SessionEmail(c), c.Param(param)) {
+				return unauthorized(c, http.StatusForbidden, "You can only access your own record")
+			}
+			return next(c)
+		}
+	}
+}
+
+// SessionEmail returns the email of the verified session, if any.
+func SessionEmail(c echo.Context) string {
+	email, _ := c.Get(sessionEmailKey).(string)
+	return email
+}
+
+func unauthorized(c echo.Context, code int, msg string) error {
+	return c.JSON(code, map[string]interface{}{
+		"error": msg,
+	})
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var secret = []byte("0123456789abcdef0123456789abcdef")
+
+func TestIssueAndVerify(t *testing.T) {
+	s := New(secret, time.Hour)
+
+	token, expiresAt, err := s.Issue("jane.doe@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d := time.Until(expiresAt); d <= 0 || d > time.Hour {
+		t.Errorf("unexpected expiry %v", expiresAt)
+	}
+
+	email, err := s.Verify(token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if email != "jane.doe@example.com" {
+		t.Errorf("Verify() = %s, want jane.doe@example.com", email)
+	}
+
+	if _, err := New([]byte("another-secret-another-secret-xx"), time.Hour).Verify(token); err == nil {
+		t.Errorf("expected error for a token signed with another secret")
+	}
+	if _, err := s.Verify(token + "x"); err == nil {
+		t.Errorf("expected error for a tampered token")
+	}
+
+	expired := New(secret, time.Hour)
+	expired.now = func() time.Time { return time.Now().Add(-2 * time.Hour) }
+	token, _, err = expired.Issue("jane.doe@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.Verify(token); err == nil {
+		t.Errorf("expected error for an expired token")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	s := New(secret, time.Hour)
+	token, _, err := s.Issue("Jane.Doe@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	e := echo.New()
+	e.GET("/users/:email", func(c echo.Context) error {
+		return c.String(http.StatusOK, SessionEmail(c))
+	}, s.Middleware(), RequireSelf("email"))
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		code   int
+	}{
+		{"no token", "/users/jane.doe@example.com", "", http.StatusUnauthorized},
+		{"not bearer", "/users/jane.doe@example.com", "Basic " + token, http.StatusUnauthorized},
+		{"invalid token", "/users/jane.doe@example.com", "Bearer nonsense", http.StatusUnauthorized},
+		{"other record", "/users/john.roe@example.com", "Bearer " + token, http.StatusForbidden},
+		{"own record", "/users/jane.doe@example.com", "Bearer " + token, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(echo.HeaderAuthorization, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.code {
+				t.Errorf("got status %d, want %d: %s", rec.Code, tt.code, rec.Body.String())
+			}
+		})
+	}
+}
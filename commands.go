@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Reskill-2022/volunteering/audit"
+	"github.com/Reskill-2022/volunteering/config"
+	"github.com/Reskill-2022/volunteering/export"
+	"github.com/Reskill-2022/volunteering/forms"
+	"github.com/Reskill-2022/volunteering/importer"
+	"github.com/Reskill-2022/volunteering/linkedin/linkedintest"
+	"github.com/Reskill-2022/volunteering/repository"
+	"github.com/Reskill-2022/volunteering/requests"
+	"github.com/Reskill-2022/volunteering/taxonomy"
+)
+
+type command func(logger zerolog.Logger, args []string) error
+
+var commands = map[string]command{
+	"config":        configCommand,
+	"export":        exportCommand,
+	"fake-linkedin": fakeLinkedIn,
+	"import":        importCommand,
+	"migrate":       migrate,
+	"reconcile":     reconcile,
+}
+
+func runCommand(logger zerolog.Logger, name string, args []string) error {
+	cmd, ok := commands[name]
+	if !ok {
+		names := make([]string, 0, len(commands))
+		for n := range commands {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown command '%s', available commands: %v", name, names)
+	}
+	return cmd(logger, args)
+}
+
+// fakeLinkedIn serves the linkedintest stub so the API can be run against it
+// by pointing LINKEDIN_AUTH_URL and LINKEDIN_API_URL at addr.
+func fakeLinkedIn(logger zerolog.Logger, args []string) error {
+	fs := flag.NewFlagSet("fake-linkedin", flag.ContinueOnError)
+	addr := fs.String("addr", ":9000", "address to listen on")
+	fixturesFile := fs.String("fixtures", "", "JSON file of fixture profiles (defaults to the built-in fixtures)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	fixtures := linkedintest.DefaultFixtures()
+	if *fixturesFile != "" {
+		loaded, err := linkedintest.LoadFixtures(*fixturesFile)
+		if err != nil {
+			return err
+		}
+		fixtures = loaded
+	}
+
+	for _, f := range fixtures {
+		logger.Info().Msgf("Fake LinkedIn: auth code '%s' -> %s (fail: '%s')", f.Code, f.Email, f.Fail)
+	}
+	logger.Info().Msgf("Fake LinkedIn listening on %s", *addr)
+
+	return http.ListenAndServe(*addr, linkedintest.NewServer(fixtures...))
+}
+
+// reconcile diffs every mirror against the primary Firestore project once,
+// repairs any drift and prints the reports.
+func reconcile(logger zerolog.Logger, args []string) error {
+	cfg, err := config.New()
+	if err != nil {
+		return err
+	}
+	if cfg.Storage.Driver != config.DriverFirestore {
+		return fmt.Errorf("reconcile requires the '%s' storage driver", config.DriverFirestore)
+	}
+
+	// Reconcile does not touch the replication log, so it is left unopened
+	// to avoid contending with a running server for its file lock.
+	users, err := repository.NewUserRepository(logger, nil, repository.FirestoreOptions(cfg.Firestore))
+	if err != nil {
+		return err
+	}
+
+	reports, err := users.Reconcile(context.Background())
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if encErr := enc.Encode(reports); encErr != nil {
+		return encErr
+	}
+	return err
+}
+
+// configCommand implements "config check", which prints the effective
+// configuration with secrets redacted and lists every validation problem.
+func configCommand(logger zerolog.Logger, args []string) error {
+	if len(args) == 0 || args[0] != "check" {
+		return fmt.Errorf("usage: config check [-config file]")
+	}
+
+	fs := flag.NewFlagSet("config check", flag.ContinueOnError)
+	path := fs.String("config", os.Getenv(config.File), "YAML config file layered under the environment")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	cfg, err := config.Check(*path)
+	if cfg == nil {
+		return err
+	}
+
+	out, marshalErr := yaml.Marshal(cfg.Redacted())
+	if marshalErr != nil {
+		return marshalErr
+	}
+	fmt.Print(string(out))
+
+	if err != nil {
+		return err
+	}
+	fmt.Println("# configuration is valid")
+	return nil
+}
+
+// exportCommand writes the volunteers matching -query (in the query string
+// format of GET /volunteering/users) to -o or stdout.
+func exportCommand(logger zerolog.Logger, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", export.CSV, "output format: csv, xlsx or jsonl")
+	out := fs.String("o", "", "output file (defaults to stdout)")
+	rawQuery := fs.String("query", "", "filters and sort, e.g. 'enrolled=true&state=Texas&sort=-created_at'")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	values, err := url.ParseQuery(*rawQuery)
+	if err != nil {
+		return fmt.Errorf("invalid -query: %w", err)
+	}
+	query, err := requests.ParseUserQuery(values)
+	if err != nil {
+		return err
+	}
+	if err := export.ValidFormat(*format); err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out == "" {
+		// keep logs out of the export
+		logger = logger.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	} else {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	cfg, err := config.New()
+	if err != nil {
+		return err
+	}
+	users, err := openUsers(logger, cfg)
+	if err != nil {
+		return err
+	}
+
+	n, err := export.Users(context.Background(), w, users, export.Options{Format: *format, Query: query})
+	if err != nil {
+		return err
+	}
+	logger.Info().Msgf("Exported %d users", n)
+	return nil
+}
+
+// openUsers opens the configured user repository like the other commands,
+// without the audit log.
+func openUsers(logger zerolog.Logger, cfg *config.Config) (repository.UserRepositoryInterface, error) {
+	rc, err := repository.NewCommandContainer(logger, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if audited, ok := rc.UserRepository.(*repository.AuditedUserRepository); ok {
+		return audited.Unwrap(), nil
+	}
+	return rc.UserRepository, nil
+}
+
+// importCommand creates the volunteers listed in a CSV or JSON Lines file
+// and writes a per-row report as CSV to -report or stdout.
+func importCommand(logger zerolog.Logger, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	file := fs.String("file", "", "CSV or JSON Lines file of volunteers")
+	format := fs.String("format", "", "csv or jsonl (defaults to the file extension)")
+	dryRun := fs.Bool("dry-run", false, "validate and report without writing")
+	batchSize := fs.Int("batch", importer.DefaultBatchSize, "number of users written concurrently")
+	reportFile := fs.String("report", "", "report file (defaults to stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return fmt.Errorf("usage: import -file volunteers.csv [-dry-run] [-report report.csv]")
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(*file), ".")
+	}
+
+	in, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var out io.Writer = os.Stdout
+	if *reportFile == "" {
+		// keep logs out of the report
+		logger = logger.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	} else {
+		f, err := os.Create(*reportFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	cfg, err := config.New()
+	if err != nil {
+		return err
+	}
+	// write mirrors before returning, as the process exits right after
+	cfg.Firestore.WritePolicy = config.WriteAll
+	rc, err := repository.NewCommandContainer(logger, cfg)
+	if err != nil {
+		return err
+	}
+
+	options, err := taxonomy.NewCatalog(rc.Taxonomy).Get(context.Background())
+	if err != nil {
+		return err
+	}
+	// rows answer the current form, as applications do
+	form, err := forms.NewRegistry(rc.Forms).Current(context.Background())
+	if err != nil {
+		return err
+	}
+
+	ctx := audit.WithActor(context.Background(), audit.Actor{Name: "import " + filepath.Base(*file), Role: audit.RoleSystem})
+	report, err := importer.Import(ctx, in, rc.UserRepository, importer.Options{
+		Format:    *format,
+		DryRun:    *dryRun,
+		BatchSize: *batchSize,
+		Form:      form,
+		Taxonomy:  options,
+	})
+	if err != nil {
+		return err
+	}
+
+	w := csv.NewWriter(out)
+	_ = w.Write([]string{"line", "email", "status", "message"})
+	for _, row := range report.Rows {
+		_ = w.Write([]string{strconv.Itoa(row.Line), row.Email, row.Status, row.Message})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+
+	logger.Info().Msgf("Import of %s finished (dry run: %t): %v", *file, *dryRun, report.Counts)
+	if report.Counts[importer.StatusFailed] > 0 {
+		return fmt.Errorf("%d rows failed to import", report.Counts[importer.StatusFailed])
+	}
+	return nil
+}
+
+// migrate converts users whose volunteer areas and means are still stored as
+// comma-separated strings.
+func migrate(logger zerolog.Logger, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "count the users to migrate without changing them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.New()
+	if err != nil {
+		return err
+	}
+	users, err := openUsers(logger, cfg)
+	if err != nil {
+		return err
+	}
+
+	migrator, ok := users.(repository.ListMigrator)
+	if !ok {
+		return fmt.Errorf("the '%s' storage driver has nothing to migrate", cfg.Storage.Driver)
+	}
+
+	n, err := migrator.MigrateVolunteerLists(context.Background(), *dryRun)
+	if err != nil {
+		return err
+	}
+	logger.Info().Msgf("Migrated volunteer lists of %d users (dry run: %t)", n, *dryRun)
+	return nil
+}
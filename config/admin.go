@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AdminAPIKeys is the secret holding the admin API keys, see ParseAPIKeys.
+const AdminAPIKeys = "ADMIN_API_KEYS"
+
+// Admin roles, from least to most privileged. Each role can do everything the
+// roles before it can.
+const (
+	AdminViewer      = "viewer"
+	AdminCoordinator = "coordinator"
+	AdminAdmin       = "admin"
+)
+
+// minAPIKeyLength is the minimum length of an admin API key.
+const minAPIKeyLength = 24
+
+// APIKey grants the holder of Key access to the admin API with Role.
+type APIKey struct {
+	Name string `yaml:"name"`
+	Role string `yaml:"role"`
+	Key  string `yaml:"key" secret:"true"`
+}
+
+// ParseAPIKeys parses a comma-separated list of name:role:key entries.
+func ParseAPIKeys(spec string) ([]APIKey, error) {
+	var keys []APIKey
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.SplitN(item, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("invalid api key for '%s', expected name:role:key", parts[0])
+		}
+		keys = append(keys, APIKey{Name: parts[0], Role: parts[1], Key: parts[2]})
+	}
+
+	if err := validateAPIKeys(keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func validateAPIKeys(keys []APIKey) error {
+	names := make(map[string]bool)
+	secrets := make(map[string]bool)
+
+	for _, k := range keys {
+		if k.Name == "" {
+			return fmt.Errorf("every api key needs a name")
+		}
+		switch k.Role {
+		case AdminViewer, AdminCoordinator, AdminAdmin:
+		default:
+			return fmt.Errorf("api key '%s' has unknown role '%s'", k.Name, k.Role)
+		}
+		if len(k.Key) < minAPIKeyLength {
+			return fmt.Errorf("api key '%s' must be at least %d characters", k.Name, minAPIKeyLength)
+		}
+		if names[k.Name] {
+			return fmt.Errorf("api key '%s' is listed more than once", k.Name)
+		}
+		if secrets[k.Key] {
+			return fmt.Errorf("api key '%s' reuses the key of another entry", k.Name)
+		}
+		names[k.Name] = true
+		secrets[k.Key] = true
+	}
+	return nil
+}
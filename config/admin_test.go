@@ -0,0 +1,37 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAPIKeys(t *testing.T) {
+	key := strings.Repeat("k", minAPIKeyLength)
+
+	testCases := []struct {
+		spec    string
+		want    int
+		wantErr bool
+	}{
+		{"ops:admin:" + key, 1, false},
+		{"ops:admin:" + key + ", dashboard:viewer:" + key + "2", 2, false},
+		{"ops:admin:" + key + ":with:colons", 1, false},
+		{"", 0, false},
+		{"ops:owner:" + key, 0, true},
+		{"ops:admin:short", 0, true},
+		{"ops:admin:" + key + ",ops:viewer:" + key + "2", 0, true},
+		{"ops:admin:" + key + ",other:viewer:" + key, 0, true},
+		{"ops:admin", 0, true},
+	}
+
+	for _, tc := range testCases {
+		got, err := ParseAPIKeys(tc.spec)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParseAPIKeys(%s) error = %v, wantErr %t", tc.spec, err, tc.wantErr)
+			continue
+		}
+		if len(got) != tc.want {
+			t.Errorf("ParseAPIKeys(%s) returned %d keys, want %d", tc.spec, len(got), tc.want)
+		}
+	}
+}
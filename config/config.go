@@ -1,34 +1,508 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/mail"
 	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Reskill-2022/volunteering/constants"
+)
+
+// File is the environment variable naming an optional YAML config file.
+// Values from the environment take precedence over the file.
+const File = "CONFIG_FILE"
+
+const (
+	DriverFirestore = "firestore"
+	DriverBolt      = "bolt"
+	DriverMemory    = "memory"
+)
+
+const (
+	FlowLegacy = "legacy"
+	FlowOIDC   = "oidc"
 )
 
 const (
-	Port            = "PORT"
-	ClientID        = "CLIENT_ID"
-	ClientSecret    = "CLIENT_SECRET"
-	ServiceAccount1 = "SERVICE_ACCOUNT_1"
-	ServiceAccount2 = "SERVICE_ACCOUNT_2"
+	MailNone = "none"
+	MailSMTP = "smtp"
+	MailFile = "file"
 )
 
-type Environment map[string]string
-
-func New() (Environment, error) {
-	env := make(Environment)
-	for _, key := range []string{
-		Port,
-		ClientID,
-		ClientSecret,
-		ServiceAccount1,
-		ServiceAccount2,
-	} {
-		v, ok := os.LookupEnv(key)
+const redacted = "[REDACTED]"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+type (
+	// Config is the service configuration. Each field is filled, in
+	// increasing order of precedence, from its default, the config file (yaml
+	// tag) and the environment (env tag, prefixed by the env tags of the
+	// enclosing structs). Fields tagged secret are resolved with LookupSecret
+	// and redacted by Redacted.
+	Config struct {
+		Port        string      `yaml:"port" env:"PORT"`
+		LinkedIn    LinkedIn    `yaml:"linkedin"`
+		Google      OAuthClient `yaml:"google" env:"GOOGLE_"`
+		GitHub      OAuthClient `yaml:"github" env:"GITHUB_"`
+		Storage     Storage     `yaml:"storage"`
+		Firestore   Firestore   `yaml:"firestore"`
+		Replication Replication `yaml:"replication"`
+		Session     Session     `yaml:"session"`
+		Admin       Admin       `yaml:"admin"`
+		Mail        Mail        `yaml:"mail"`
+		Jobs        Jobs        `yaml:"jobs"`
+		Webhooks    []Webhook   `yaml:"webhooks"`
+	}
+
+	LinkedIn struct {
+		ClientID     string `yaml:"client_id" env:"CLIENT_ID"`
+		ClientSecret string `yaml:"client_secret" env:"CLIENT_SECRET" secret:"true"`
+		AuthURL      string `yaml:"auth_url" env:"LINKEDIN_AUTH_URL"`
+		APIURL       string `yaml:"api_url" env:"LINKEDIN_API_URL"`
+		Flow         string `yaml:"flow" env:"LINKEDIN_FLOW"`
+	}
+
+	// OAuthClient holds the credentials of an optional identity provider. The
+	// provider is enabled when ClientID is set.
+	OAuthClient struct {
+		ClientID     string `yaml:"client_id" env:"CLIENT_ID"`
+		ClientSecret string `yaml:"client_secret" env:"CLIENT_SECRET" secret:"true"`
+	}
+
+	Storage struct {
+		Driver string `yaml:"driver" env:"STORAGE_DRIVER"`
+		Path   string `yaml:"path" env:"STORAGE_PATH"`
+	}
+
+	Firestore struct {
+		// Replicas can be set from the environment with FIRESTORE_REPLICAS,
+		// see ParseReplicas.
+		Replicas    []Replica `yaml:"replicas"`
+		WritePolicy string    `yaml:"write_policy" env:"REPLICA_WRITE_POLICY"`
+		ReadPolicy  string    `yaml:"read_policy" env:"REPLICA_READ_POLICY"`
+	}
+
+	Replication struct {
+		LogPath           string        `yaml:"log_path" env:"REPLICATION_LOG_PATH"`
+		RetryInterval     time.Duration `yaml:"retry_interval" env:"REPLICATION_RETRY_INTERVAL"`
+		ReconcileInterval time.Duration `yaml:"reconcile_interval" env:"RECONCILE_INTERVAL"`
+	}
+
+	// Session configures the tokens volunteers receive after signing in.
+	Session struct {
+		Secret string        `yaml:"secret" env:"SESSION_SECRET" secret:"true"`
+		TTL    time.Duration `yaml:"ttl" env:"SESSION_TTL"`
+	}
+
+	Admin struct {
+		// APIKeys can be set from the ADMIN_API_KEYS secret, see
+		// ParseAPIKeys. The admin API rejects every request when it is empty.
+		APIKeys []APIKey `yaml:"api_keys"`
+	}
+)
+
+// Mail configures the notification emails. The file driver writes each
+// message to Dir instead of sending it, for development.
+type Mail struct {
+	Driver        string        `yaml:"driver" env:"MAIL_DRIVER"`
+	From          string        `yaml:"from" env:"MAIL_FROM"`
+	Dir           string        `yaml:"dir" env:"MAIL_DIR"`
+	RetryInterval time.Duration `yaml:"retry_interval" env:"MAIL_RETRY_INTERVAL"`
+	SMTP          SMTP          `yaml:"smtp" env:"SMTP_"`
+}
+
+type SMTP struct {
+	Host     string `yaml:"host" env:"HOST"`
+	Port     string `yaml:"port" env:"PORT"`
+	Username string `yaml:"username" env:"USERNAME"`
+	Password string `yaml:"password" env:"PASSWORD" secret:"true"`
+}
+
+// Jobs configures the background job queue, see package jobs.
+type Jobs struct {
+	Path          string        `yaml:"path" env:"JOBS_PATH"`
+	Workers       int           `yaml:"workers" env:"JOBS_WORKERS"`
+	RetryInterval time.Duration `yaml:"retry_interval" env:"JOBS_RETRY_INTERVAL"`
+	MaxAttempts   int           `yaml:"max_attempts" env:"JOBS_MAX_ATTEMPTS"`
+}
+
+// minSessionSecretLength is the minimum length of the HMAC session secret.
+const minSessionSecretLength = 32
+
+// ValidationError lists every problem found in a Config.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid configuration:\n  - %s", strings.Join(e.Problems, "\n  - "))
+}
+
+// Defaults returns the configuration used when nothing else is set.
+func Defaults() Config {
+	return Config{
+		Port: "8080",
+		LinkedIn: LinkedIn{
+			Flow: FlowLegacy,
+		},
+		Storage: Storage{
+			Driver: DriverFirestore,
+			Path:   "volunteering.db",
+		},
+		Firestore: Firestore{
+			Replicas:    defaultReplicas(),
+			WritePolicy: WritePrimaryThenAsync,
+			ReadPolicy:  ReadPrimary,
+		},
+		Replication: Replication{
+			LogPath:           "replication.db",
+			RetryInterval:     30 * time.Second,
+			ReconcileInterval: 6 * time.Hour,
+		},
+		Session: Session{
+			TTL: 24 * time.Hour,
+		},
+		Jobs: Jobs{
+			Path:          "jobs.db",
+			Workers:       4,
+			RetryInterval: 10 * time.Second,
+			MaxAttempts:   8,
+		},
+		Mail: Mail{
+			Driver:        MailNone,
+			From:          constants.DefaultSourceEmail,
+			Dir:           "mail",
+			RetryInterval: 30 * time.Second,
+			SMTP: SMTP{
+				Port: "587",
+			},
+		},
+	}
+}
+
+// New loads the configuration from the file named by CONFIG_FILE, if any,
+// and the environment.
+func New() (*Config, error) {
+	return Load(os.Getenv(File))
+}
+
+// Load layers the environment over the YAML file at path (which may be
+// empty) over Defaults, resolves secrets and validates the result.
+func Load(path string) (*Config, error) {
+	cfg, err := Check(path)
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Check is Load, except that an invalid config is returned alongside the
+// *ValidationError describing it so that it can be reported. The config is
+// nil only if the file could not be read.
+func Check(path string) (*Config, error) {
+	cfg := Defaults()
+	var problems []string
+
+	if path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := yaml.Unmarshal(raw, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+
+	problems = append(problems, applyEnv(reflect.ValueOf(&cfg).Elem(), "")...)
+
+	if spec, ok := os.LookupEnv(FirestoreReplicas); ok && spec != "" {
+		replicas, err := ParseReplicas(spec)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", FirestoreReplicas, err))
+		} else {
+			cfg.Firestore.Replicas = replicas
+		}
+	}
+
+	spec, ok, err := LookupSecret(AdminAPIKeys)
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+	if ok && spec != "" {
+		keys, err := ParseAPIKeys(spec)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", AdminAPIKeys, err))
+		} else {
+			cfg.Admin.APIKeys = keys
+		}
+	}
+
+	if cfg.Storage.Driver == DriverFirestore {
+		for i, r := range cfg.Firestore.Replicas {
+			if r.CredentialsKey == "" {
+				continue
+			}
+			sa, ok, err := LookupSecret(r.CredentialsKey)
+			if err != nil {
+				problems = append(problems, err.Error())
+			}
+			if ok {
+				cfg.Firestore.Replicas[i].Credentials = sa
+			}
+		}
+	}
+
+	for i, w := range cfg.Webhooks {
+		if w.SecretKey == "" {
+			continue
+		}
+		secret, ok, err := LookupSecret(w.SecretKey)
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+		if ok {
+			cfg.Webhooks[i].Secret = secret
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		problems = append(problems, err.(*ValidationError).Problems...)
+	}
+
+	if len(problems) > 0 {
+		return &cfg, &ValidationError{Problems: problems}
+	}
+	return &cfg, nil
+}
+
+// applyEnv overrides the fields of v that have an env tag and are set in the
+// environment. It returns a problem for each value that could not be parsed.
+func applyEnv(v reflect.Value, prefix string) []string {
+	var problems []string
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		key, ok := field.Tag.Lookup("env")
 		if !ok {
-			return nil, fmt.Errorf("can't find '%s' in environment", key)
+			if field.Type.Kind() == reflect.Struct {
+				problems = append(problems, applyEnv(v.Field(i), prefix)...)
+			}
+			continue
+		}
+		key = prefix + key
+
+		if field.Type.Kind() == reflect.Struct {
+			problems = append(problems, applyEnv(v.Field(i), key)...)
+			continue
+		}
+
+		var (
+			value string
+			found bool
+		)
+		if field.Tag.Get("secret") == "true" {
+			var err error
+			value, found, err = LookupSecret(key)
+			if err != nil {
+				problems = append(problems, err.Error())
+				continue
+			}
+		} else {
+			value, found = os.LookupEnv(key)
+		}
+		if !found || value == "" {
+			continue
+		}
+
+		switch {
+		case field.Type == durationType:
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: '%s' is not a duration", key, value))
+				continue
+			}
+			v.Field(i).SetInt(int64(d))
+		case field.Type.Kind() == reflect.Int:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: '%s' is not a number", key, value))
+				continue
+			}
+			v.Field(i).SetInt(int64(n))
+		case field.Type.Kind() == reflect.String:
+			v.Field(i).SetString(value)
+		}
+	}
+
+	return problems
+}
+
+// Validate reports every problem with c at once.
+func (c *Config) Validate() error {
+	var problems []string
+	problemf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if c.Port == "" {
+		problemf("PORT is required")
+	}
+
+	if c.LinkedIn.ClientID == "" {
+		problemf("CLIENT_ID is required")
+	}
+	if c.LinkedIn.ClientSecret == "" {
+		problemf("CLIENT_SECRET is required")
+	}
+	if c.LinkedIn.Flow != FlowLegacy && c.LinkedIn.Flow != FlowOIDC {
+		problemf("LINKEDIN_FLOW: unknown linkedin flow '%s'", c.LinkedIn.Flow)
+	}
+
+	if len(c.Session.Secret) < minSessionSecretLength {
+		problemf("SESSION_SECRET must be at least %d characters", minSessionSecretLength)
+	}
+	if c.Session.TTL <= 0 {
+		problemf("SESSION_TTL must be a positive duration")
+	}
+
+	if err := validateAPIKeys(c.Admin.APIKeys); err != nil {
+		problemf("%s: %v", AdminAPIKeys, err)
+	}
+
+	if (c.Google.ClientID == "") != (c.Google.ClientSecret == "") {
+		problemf("GOOGLE_CLIENT_ID and GOOGLE_CLIENT_SECRET must be set together")
+	}
+	if (c.GitHub.ClientID == "") != (c.GitHub.ClientSecret == "") {
+		problemf("GITHUB_CLIENT_ID and GITHUB_CLIENT_SECRET must be set together")
+	}
+
+	switch c.Mail.Driver {
+	case MailNone:
+	case MailSMTP:
+		if c.Mail.SMTP.Host == "" || c.Mail.SMTP.Port == "" {
+			problemf("SMTP_HOST and SMTP_PORT are required for the smtp mail driver")
+		}
+	case MailFile:
+		if c.Mail.Dir == "" {
+			problemf("MAIL_DIR is required for the file mail driver")
+		}
+	default:
+		problemf("MAIL_DRIVER: unknown mail driver '%s'", c.Mail.Driver)
+	}
+	if c.Mail.Driver != MailNone {
+		if _, err := mail.ParseAddress(c.Mail.From); err != nil {
+			problemf("MAIL_FROM: '%s' is not an email address", c.Mail.From)
+		}
+		if c.Mail.RetryInterval <= 0 {
+			problemf("MAIL_RETRY_INTERVAL must be a positive duration")
+		}
+	}
+
+	problems = append(problems, validateWebhooks(c.Webhooks)...)
+
+	if c.Jobs.Path == "" {
+		problemf("JOBS_PATH is required")
+	}
+	if c.Jobs.Workers < 1 {
+		problemf("JOBS_WORKERS must be at least 1")
+	}
+	if c.Jobs.RetryInterval <= 0 {
+		problemf("JOBS_RETRY_INTERVAL must be a positive duration")
+	}
+	if c.Jobs.MaxAttempts < 1 {
+		problemf("JOBS_MAX_ATTEMPTS must be at least 1")
+	}
+
+	switch c.Storage.Driver {
+	case DriverFirestore:
+		problems = append(problems, c.Firestore.validate()...)
+		if c.Replication.LogPath == "" {
+			problemf("REPLICATION_LOG_PATH is required")
+		}
+		if c.Replication.RetryInterval <= 0 {
+			problemf("REPLICATION_RETRY_INTERVAL must be a positive duration")
+		}
+		if c.Replication.ReconcileInterval <= 0 {
+			problemf("RECONCILE_INTERVAL must be a positive duration")
+		}
+	case DriverBolt:
+		if c.Storage.Path == "" {
+			problemf("STORAGE_PATH is required for the bolt driver")
+		}
+	case DriverMemory:
+	default:
+		problemf("STORAGE_DRIVER: unknown storage driver '%s'", c.Storage.Driver)
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func (f Firestore) validate() []string {
+	var problems []string
+
+	if err := validateReplicas(f.Replicas); err != nil {
+		problems = append(problems, fmt.Sprintf("%s: %v", FirestoreReplicas, err))
+	}
+	for _, r := range f.Replicas {
+		switch {
+		case r.Credentials == "":
+			problems = append(problems, fmt.Sprintf("can't find '%s' for replica %s", r.CredentialsKey, r.Name))
+		case !json.Valid([]byte(r.Credentials)):
+			problems = append(problems, fmt.Sprintf("'%s' is not a valid service account JSON", r.CredentialsKey))
+		}
+	}
+
+	switch f.WritePolicy {
+	case WriteAll, WriteQuorum, WritePrimaryThenAsync:
+	default:
+		problems = append(problems, fmt.Sprintf("REPLICA_WRITE_POLICY: unknown replica write policy '%s'", f.WritePolicy))
+	}
+
+	switch f.ReadPolicy {
+	case ReadPrimary, ReadNearest, ReadFallbackOnError:
+	default:
+		problems = append(problems, fmt.Sprintf("REPLICA_READ_POLICY: unknown replica read policy '%s'", f.ReadPolicy))
+	}
+
+	return problems
+}
+
+// Redacted returns a copy of c with every secret replaced, safe to print.
+func (c Config) Redacted() Config {
+	redact(reflect.ValueOf(&c).Elem())
+	return c
+}
+
+func redact(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.Tag.Get("secret") == "true" && field.Type.Kind() == reflect.String {
+				if v.Field(i).String() != "" {
+					v.Field(i).SetString(redacted)
+				}
+				continue
+			}
+			redact(v.Field(i))
+		}
+	case reflect.Slice:
+		// copy so that redacting does not modify the original's backing array
+		copied := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(copied, v)
+		v.Set(copied)
+		for i := 0; i < v.Len(); i++ {
+			redact(v.Index(i))
 		}
-		env[key] = v
 	}
-	return env, nil
 }
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadLayersEnvironmentOverFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(`
+port: "9000"
+linkedin:
+  client_id: file-client
+  flow: oidc
+storage:
+  driver: bolt
+  path: /var/lib/volunteering.db
+replication:
+  retry_interval: 1m
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("CLIENT_SECRET", "env-secret")
+	t.Setenv("SESSION_SECRET", "0123456789abcdef0123456789abcdef")
+	t.Setenv("STORAGE_PATH", "/tmp/override.db")
+	t.Setenv("JOBS_WORKERS", "8")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.Port != "9000" || cfg.LinkedIn.ClientID != "file-client" || cfg.LinkedIn.Flow != FlowOIDC {
+		t.Errorf("expected values from file, got %+v", cfg)
+	}
+	if cfg.LinkedIn.ClientSecret != "env-secret" || cfg.Storage.Path != "/tmp/override.db" {
+		t.Errorf("expected environment to override file, got %+v", cfg)
+	}
+	if cfg.Replication.RetryInterval != time.Minute || cfg.Replication.ReconcileInterval != 6*time.Hour {
+		t.Errorf("expected file durations layered over defaults, got %+v", cfg.Replication)
+	}
+	if cfg.Jobs.Workers != 8 || cfg.Jobs.MaxAttempts != 8 {
+		t.Errorf("expected numbers from the environment, got %+v", cfg.Jobs)
+	}
+}
+
+func TestCheckReportsEveryProblem(t *testing.T) {
+	t.Setenv("GOOGLE_CLIENT_ID", "google")
+	t.Setenv("REPLICA_READ_POLICY", "random")
+	t.Setenv("RECONCILE_INTERVAL", "often")
+	t.Setenv("JOBS_WORKERS", "many")
+
+	cfg, err := Check("")
+	if cfg == nil {
+		t.Fatalf("expected the resolved config to be returned")
+	}
+
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+
+	for _, want := range []string{
+		"RECONCILE_INTERVAL",
+		"JOBS_WORKERS: 'many' is not a number",
+		"CLIENT_ID is required",
+		"CLIENT_SECRET is required",
+		"GOOGLE_CLIENT_ID and GOOGLE_CLIENT_SECRET",
+		"SERVICE_ACCOUNT_1",
+		"SERVICE_ACCOUNT_2",
+		"REPLICA_READ_POLICY",
+		"SESSION_SECRET",
+	} {
+		if !strings.Contains(verr.Error(), want) {
+			t.Errorf("expected a problem mentioning %s, got:\n%s", want, verr.Error())
+		}
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Defaults()
+	cfg.LinkedIn.ClientSecret = "linkedin-secret"
+	cfg.Session.Secret = "session-secret"
+	cfg.Firestore.Replicas[0].Credentials = `{"private_key": "secret"}`
+
+	out := cfg.Redacted()
+	if out.LinkedIn.ClientSecret != redacted || out.Session.Secret != redacted || out.Firestore.Replicas[0].Credentials != redacted {
+		t.Errorf("expected secrets to be redacted, got %+v", out)
+	}
+	if out.Google.ClientSecret != "" {
+		t.Errorf("expected empty secrets to stay empty, got %q", out.Google.ClientSecret)
+	}
+	if cfg.LinkedIn.ClientSecret != "linkedin-secret" || cfg.Firestore.Replicas[0].Credentials == redacted {
+		t.Errorf("expected the original config to be left untouched")
+	}
+}
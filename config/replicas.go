@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FirestoreReplicas is the environment variable overriding the replica list.
+const FirestoreReplicas = "FIRESTORE_REPLICAS"
+
+const (
+	RolePrimary  = "primary"
+	RoleMirror   = "mirror"
+	RoleReadOnly = "read-only"
+)
+
+const (
+	WriteAll              = "all"
+	WriteQuorum           = "quorum"
+	WritePrimaryThenAsync = "primary-then-async"
+)
+
+const (
+	ReadPrimary         = "primary"
+	ReadNearest         = "nearest"
+	ReadFallbackOnError = "fallback-on-error"
+)
+
+// Replica is one Firestore project the user repository fans out to.
+type Replica struct {
+	Name string `yaml:"name"`
+	Role string `yaml:"role"`
+	// CredentialsKey names the secret holding the service account, resolved
+	// with LookupSecret into Credentials.
+	CredentialsKey string `yaml:"credentials_key"`
+	Credentials    string `yaml:"credentials,omitempty" secret:"true"`
+}
+
+// defaultReplicas keeps the original two-project setup.
+func defaultReplicas() []Replica {
+	return []Replica{
+		{Name: "client1", Role: RolePrimary, CredentialsKey: "SERVICE_ACCOUNT_1"},
+		{Name: "client2", Role: RoleMirror, CredentialsKey: "SERVICE_ACCOUNT_2"},
+	}
+}
+
+// ParseReplicas parses a comma-separated list of name:role:CREDENTIALS_KEY
+// entries. Exactly one replica must be the primary.
+func ParseReplicas(spec string) ([]Replica, error) {
+	var replicas []Replica
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.Split(item, ":")
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("invalid replica '%s', expected name:role:CREDENTIALS_KEY", item)
+		}
+		replicas = append(replicas, Replica{Name: parts[0], Role: parts[1], CredentialsKey: parts[2]})
+	}
+
+	if err := validateReplicas(replicas); err != nil {
+		return nil, err
+	}
+	return replicas, nil
+}
+
+func validateReplicas(replicas []Replica) error {
+	names := make(map[string]bool)
+	primaries := 0
+
+	for _, r := range replicas {
+		if r.Name == "" || r.CredentialsKey == "" {
+			return fmt.Errorf("every replica needs a name and credentials key")
+		}
+		switch r.Role {
+		case RolePrimary:
+			primaries++
+		case RoleMirror, RoleReadOnly:
+		default:
+			return fmt.Errorf("replica '%s' has unknown role '%s'", r.Name, r.Role)
+		}
+		if names[r.Name] {
+			return fmt.Errorf("replica '%s' is listed more than once", r.Name)
+		}
+		names[r.Name] = true
+	}
+
+	if primaries != 1 {
+		return fmt.Errorf("expected exactly one primary replica, got %d", primaries)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package config
+
+import "testing"
+
+func TestParseReplicas(t *testing.T) {
+	testCases := []struct {
+		spec    string
+		want    int
+		wantErr bool
+	}{
+		{"client1:primary:SERVICE_ACCOUNT_1,client2:mirror:SERVICE_ACCOUNT_2", 2, false},
+		{"main:primary:SA_MAIN, eu:mirror:SA_EU, reports:read-only:SA_REPORTS", 3, false},
+		{"main:primary:SA_MAIN", 1, false},
+		{"a:mirror:SA_A", 0, true},
+		{"a:primary:SA_A,b:primary:SA_B", 0, true},
+		{"a:primary:SA_A,a:mirror:SA_B", 0, true},
+		{"a:leader:SA_A", 0, true},
+		{"a:primary", 0, true},
+	}
+
+	for _, tc := range testCases {
+		got, err := ParseReplicas(tc.spec)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParseReplicas(%s) error = %v, wantErr %t", tc.spec, err, tc.wantErr)
+			continue
+		}
+		if len(got) != tc.want {
+			t.Errorf("ParseReplicas(%s) returned %d replicas, want %d", tc.spec, len(got), tc.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SecretsDir is a directory of mounted secrets, one file per key, such as a
+// Docker or Kubernetes secrets volume.
+const SecretsDir = "SECRETS_DIR"
+
+// LookupSecret resolves key from, in order:
+//
+//   - the environment variable key itself,
+//   - a file named by key + "_FILE",
+//   - base64 in key + "_BASE64",
+//   - a file named key in SECRETS_DIR.
+//
+// The secret is only ever held in memory.
+func LookupSecret(key string) (string, bool, error) {
+	if v, ok := os.LookupEnv(key); ok {
+		return v, true, nil
+	}
+
+	if path, ok := os.LookupEnv(key + "_FILE"); ok {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to read '%s_FILE': %w", key, err)
+		}
+		return strings.TrimSpace(string(raw)), true, nil
+	}
+
+	if encoded, ok := os.LookupEnv(key + "_BASE64"); ok {
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return "", false, fmt.Errorf("failed to decode '%s_BASE64': %w", key, err)
+		}
+		return string(raw), true, nil
+	}
+
+	if dir, ok := os.LookupEnv(SecretsDir); ok && dir != "" {
+		raw, err := os.ReadFile(filepath.Join(dir, key))
+		if err == nil {
+			return strings.TrimSpace(string(raw)), true, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", false, fmt.Errorf("failed to read '%s' from %s: %w", key, SecretsDir, err)
+		}
+	}
+
+	return "", false, nil
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLookupSecret(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "FROM_DIR"), []byte("dir-secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "file-secret.json")
+	if err := os.WriteFile(file, []byte(`{"type":"service_account"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("FROM_ENV", "env-secret")
+	t.Setenv("FROM_FILE_FILE", file)
+	t.Setenv("FROM_BASE64_BASE64", "YmFzZTY0LXNlY3JldA==")
+	t.Setenv("BAD_BASE64_BASE64", "not base64!")
+	t.Setenv(SecretsDir, dir)
+
+	testCases := []struct {
+		key     string
+		want    string
+		found   bool
+		wantErr bool
+	}{
+		{key: "FROM_ENV", want: "env-secret", found: true},
+		{key: "FROM_FILE", want: `{"type":"service_account"}`, found: true},
+		{key: "FROM_BASE64", want: "base64-secret", found: true},
+		{key: "FROM_DIR", want: "dir-secret", found: true},
+		{key: "BAD_BASE64", wantErr: true},
+		{key: "MISSING"},
+	}
+
+	for _, tc := range testCases {
+		got, found, err := LookupSecret(tc.key)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("LookupSecret(%s) error = %v, wantErr %t", tc.key, err, tc.wantErr)
+			continue
+		}
+		if got != tc.want || found != tc.found {
+			t.Errorf("LookupSecret(%s) = (%q, %t), want (%q, %t)", tc.key, got, found, tc.want, tc.found)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Webhook subscribes a partner endpoint to volunteer events.
+type Webhook struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	// Events lists the event types delivered, every event when empty.
+	Events []string `yaml:"events"`
+	// SecretKey names the secret deliveries are signed with, resolved with
+	// LookupSecret into Secret.
+	SecretKey string `yaml:"secret_key"`
+	Secret    string `yaml:"secret,omitempty" secret:"true"`
+}
+
+// minWebhookSecretLength is the minimum length of a webhook signing secret.
+const minWebhookSecretLength = 16
+
+func validateWebhooks(webhooks []Webhook) []string {
+	var problems []string
+
+	seen := make(map[string]bool)
+	for _, w := range webhooks {
+		if w.Name == "" {
+			problems = append(problems, "every webhook needs a name")
+			continue
+		}
+		if seen[w.Name] {
+			problems = append(problems, fmt.Sprintf("duplicate webhook name '%s'", w.Name))
+		}
+		seen[w.Name] = true
+
+		u, err := url.Parse(w.URL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("webhook %s: '%s' is not an http(s) URL", w.Name, w.URL))
+		}
+		switch {
+		case w.SecretKey == "":
+			problems = append(problems, fmt.Sprintf("webhook %s: secret_key is required", w.Name))
+		case len(w.Secret) < minWebhookSecretLength:
+			problems = append(problems, fmt.Sprintf("webhook %s: '%s' must be at least %d characters", w.Name, w.SecretKey, minWebhookSecretLength))
+		}
+	}
+
+	return problems
+}
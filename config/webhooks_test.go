@@ -0,0 +1,45 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWebhooks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(`
+storage:
+  driver: memory
+webhooks:
+  - {name: crm, url: "https://crm.example.org/hooks", events: [volunteer.created], secret_key: CRM_WEBHOOK_SECRET}
+  - {name: crm, url: "ftp://bots.example.org", secret_key: BOTS_WEBHOOK_SECRET}
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("CLIENT_ID", "client")
+	t.Setenv("CLIENT_SECRET", "secret")
+	t.Setenv("SESSION_SECRET", "0123456789abcdef0123456789abcdef")
+	t.Setenv("CRM_WEBHOOK_SECRET", "0123456789abcdef")
+
+	cfg, err := Check(path)
+	if cfg.Webhooks[0].Secret != "0123456789abcdef" {
+		t.Errorf("expected the secret to be resolved, got %+v", cfg.Webhooks[0])
+	}
+	if cfg.Redacted().Webhooks[0].Secret != redacted {
+		t.Errorf("expected the secret to be redacted")
+	}
+
+	verr, ok := err.(*ValidationError)
+	if !ok || len(verr.Problems) != 3 {
+		t.Fatalf("expected 3 problems, got %v", err)
+	}
+	for _, want := range []string{"duplicate webhook name 'crm'", "is not an http(s) URL", "'BOTS_WEBHOOK_SECRET' must be"} {
+		if !strings.Contains(verr.Error(), want) {
+			t.Errorf("expected a problem mentioning %s, got:\n%s", want, verr.Error())
+		}
+	}
+}
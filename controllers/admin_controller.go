@@ -0,0 +1,338 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+
+	"github.com/Reskill-2022/volunteering/auth"
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/export"
+	"github.com/Reskill-2022/volunteering/forms"
+	"github.com/Reskill-2022/volunteering/model"
+	"github.com/Reskill-2022/volunteering/notify"
+	"github.com/Reskill-2022/volunteering/repository"
+	"github.com/Reskill-2022/volunteering/requests"
+	"github.com/Reskill-2022/volunteering/review"
+	"github.com/Reskill-2022/volunteering/taxonomy"
+	"github.com/Reskill-2022/volunteering/validation"
+	"github.com/Reskill-2022/volunteering/webhooks"
+)
+
+// AdminController serves the coordinators' volunteer management API. Every
+// handler expects an authenticated admin, see auth.APIKeys.
+type AdminController struct {
+	logger zerolog.Logger
+}
+
+// exportPageTimeout is how long an export may take to write each page. It
+// replaces the server's WriteTimeout, which large exports outlast.
+const exportPageTimeout = time.Minute
+
+// deadlineWriter pushes the write deadline of an export back every time a
+// page is flushed, so that an export runs as long as it keeps writing.
+type deadlineWriter struct {
+	*echo.Response
+	rc *http.ResponseController
+}
+
+func (w deadlineWriter) extend() error {
+	return w.rc.SetWriteDeadline(time.Now().Add(exportPageTimeout))
+}
+
+func (w deadlineWriter) Flush() {
+	w.Response.Flush()
+	w.extend()
+}
+
+func NewAdminController(logger zerolog.Logger) *AdminController {
+	return &AdminController{logger}
+}
+
+func (a *AdminController) HandleError(c echo.Context, err error, code int) error {
+	return handleError(a.logger, c, err, code)
+}
+
+func (a *AdminController) ListUsers(userLister repository.UserLister) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		query, err := requests.ParseUserQuery(c.QueryParams())
+		if err != nil {
+			return a.HandleError(c, err, http.StatusBadRequest)
+		}
+
+		page, err := userLister.ListUsers(ctx, query)
+		if err != nil {
+			return a.HandleError(c, err, errors.CodeFrom(err))
+		}
+
+		return HandleSuccess(c, page, http.StatusOK)
+	}
+}
+
+// ExportUsers streams the volunteers matching the ParseUserQuery filters as
+// ?format=csv (the default), xlsx or jsonl.
+func (a *AdminController) ExportUsers(userLister repository.UserLister) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		query, err := requests.ParseUserQuery(c.QueryParams())
+		if err != nil {
+			return a.HandleError(c, err, http.StatusBadRequest)
+		}
+		format := c.QueryParam("format")
+		if format == "" {
+			format = export.CSV
+		}
+		if err := export.ValidFormat(format); err != nil {
+			return a.HandleError(c, err, http.StatusBadRequest)
+		}
+
+		filename := fmt.Sprintf("volunteers-%s.%s", time.Now().UTC().Format("2006-01-02"), format)
+		res := c.Response()
+		res.Header().Set(echo.HeaderContentType, export.ContentType(format))
+		res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+
+		w := deadlineWriter{Response: res, rc: http.NewResponseController(res.Writer)}
+		if err := w.extend(); err != nil {
+			a.logger.Warn().Err(err).Msg("Export is limited by the server's write timeout")
+		}
+
+		n, err := export.Users(ctx, w, userLister, export.Options{Format: format, Query: query})
+		if err != nil && !res.Committed {
+			res.Header().Del(echo.HeaderContentDisposition)
+			return a.HandleError(c, err, errors.CodeFrom(err))
+		}
+		if err != nil {
+			// the status is already sent, so the truncated file is all the
+			// client gets
+			a.logger.Err(err).Msgf("Export failed after %d users", n)
+			return nil
+		}
+
+		admin, _ := auth.AdminFrom(c)
+		a.logger.Info().Msgf("Admin: %s (%s) exported %d users as %s", admin.Name, admin.Role, n, format)
+		return nil
+	}
+}
+
+func (a *AdminController) GetUser(userGetter repository.UserGetter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		user, err := userGetter.GetUser(ctx, c.Param("email"))
+		if err != nil {
+			return a.HandleError(c, err, errors.CodeFrom(err))
+		}
+
+		return HandleSuccess(c, user, http.StatusOK)
+	}
+}
+
+func (a *AdminController) UpdateUser(userGetter repository.UserGetter, userUpdater repository.UserUpdater, catalog *taxonomy.Catalog) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var requestBody requests.AdminUpdateUserRequest
+
+		err := json.NewDecoder(c.Request().Body).Decode(&requestBody)
+		if err != nil {
+			return a.HandleError(c, errors.New("Invalid JSON Request Body", 400), http.StatusBadRequest)
+		}
+
+		update, err := userGetter.GetUser(ctx, c.Param("email"))
+		if err != nil {
+			return a.HandleError(c, err, errors.CodeFrom(err))
+		}
+
+		setString := func(dst *string, src *string) {
+			if src != nil {
+				*dst = strings.TrimSpace(*src)
+			}
+		}
+		setString(&update.Phone, requestBody.Phone)
+		setString(&update.State, requestBody.State)
+		setString(&update.Organization, requestBody.Organization)
+		setString(&update.YearsOfExperience, requestBody.YearsOfExperience)
+		setString(&update.Representation, requestBody.Representation)
+		setString(&update.ProvidedName, requestBody.ProvidedName)
+		if requestBody.VolunteerAreas != nil {
+			update.VolunteerAreas = requestBody.VolunteerAreas
+		}
+		if requestBody.VolunteerMeans != nil {
+			update.VolunteerMeans = requestBody.VolunteerMeans
+		}
+		if requestBody.Convicted != nil {
+			update.Convicted = *requestBody.Convicted
+		}
+		if requestBody.Enrolled != nil {
+			update.Enrolled = *requestBody.Enrolled
+		}
+		if requestBody.ProfileURL != nil {
+			update.ProfileURL = ""
+			if profileURL := strings.TrimSpace(*requestBody.ProfileURL); profileURL != "" {
+				if update.ProfileURL, err = validation.LinkedInURL(profileURL); err != nil {
+					return a.HandleError(c, err, http.StatusBadRequest)
+				}
+			}
+		}
+		if requestBody.Industries != nil {
+			update.Industries = nil
+			if industries := *requestBody.Industries; len(industries) > 0 {
+				if err := validation.Industries(industries); err != nil {
+					return a.HandleError(c, err, http.StatusBadRequest)
+				}
+				update.Industries = industries
+			}
+		}
+
+		// only the answers being changed are checked, so that volunteers
+		// who applied before an option was removed can still be edited
+		options, err := catalog.Get(ctx)
+		if err != nil {
+			return a.HandleError(c, err, errors.CodeFrom(err))
+		}
+		changed := model.User{VolunteerAreas: requestBody.VolunteerAreas, VolunteerMeans: requestBody.VolunteerMeans}
+		setString(&changed.State, requestBody.State)
+		setString(&changed.YearsOfExperience, requestBody.YearsOfExperience)
+		setString(&changed.Representation, requestBody.Representation)
+		if err := options.CheckUser(changed); err != nil {
+			return a.HandleError(c, err, http.StatusBadRequest)
+		}
+
+		// keep the answers the fields are copied from in step
+		if err := forms.Sync(update, requestBody.Answers()...); err != nil {
+			return a.HandleError(c, err, errors.CodeFrom(err))
+		}
+
+		user, err := userUpdater.UpdateUser(ctx, *update)
+		if err != nil {
+			return a.HandleError(c, err, errors.CodeFrom(err))
+		}
+
+		a.logAction(c, "updated", user.Email)
+		return HandleSuccess(c, user, http.StatusOK)
+	}
+}
+
+// SetDeactivated deactivates or, with deactivated false, reactivates a
+// volunteer. Deactivated volunteers can no longer sign in or apply.
+func (a *AdminController) SetDeactivated(userGetter repository.UserGetter, userUpdater repository.UserUpdater, deactivated bool) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		update, err := userGetter.GetUser(ctx, c.Param("email"))
+		if err != nil {
+			return a.HandleError(c, err, errors.CodeFrom(err))
+		}
+
+		update.Deactivated = deactivated
+		user, err := userUpdater.UpdateUser(ctx, *update)
+		if err != nil {
+			return a.HandleError(c, err, errors.CodeFrom(err))
+		}
+
+		action := "reactivated"
+		if deactivated {
+			action = "deactivated"
+		}
+		a.logAction(c, action, user.Email)
+		return HandleSuccess(c, user, http.StatusOK)
+	}
+}
+
+// ReviewUser moves a volunteer's application through the review workflow,
+// recording the admin and their note.
+func (a *AdminController) ReviewUser(userGetter repository.UserGetter, userUpdater repository.UserUpdater, notifier *notify.Notifier, hooks *webhooks.Dispatcher) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var requestBody requests.ReviewRequest
+
+		err := json.NewDecoder(c.Request().Body).Decode(&requestBody)
+		if err != nil {
+			return a.HandleError(c, errors.New("Invalid JSON Request Body", 400), http.StatusBadRequest)
+		}
+		if requestBody.Status == "" {
+			return a.HandleError(c, errors.New("Status is required", 400), http.StatusBadRequest)
+		}
+
+		update, err := userGetter.GetUser(ctx, c.Param("email"))
+		if err != nil {
+			return a.HandleError(c, err, errors.CodeFrom(err))
+		}
+
+		admin, _ := auth.AdminFrom(c)
+		err = review.Transition(update, requestBody.Status, admin.Name, admin.Role, requestBody.Note, time.Now().UTC())
+		if err != nil {
+			return a.HandleError(c, err, errors.CodeFrom(err))
+		}
+
+		user, err := userUpdater.UpdateUser(ctx, *update)
+		if err != nil {
+			return a.HandleError(c, err, errors.CodeFrom(err))
+		}
+
+		if user.Status == model.StatusApproved || user.Status == model.StatusRejected {
+			notifier.Notify(notify.ReviewDecision, *user)
+		}
+		hooks.Emit(ctx, webhooks.StatusChanged, webhooks.StatusChangeData(*user))
+
+		a.logAction(c, "moved to "+user.Status, user.Email)
+		return HandleSuccess(c, user, http.StatusOK)
+	}
+}
+
+// GetReviews returns the status history of a volunteer's application.
+func (a *AdminController) GetReviews(userGetter repository.UserGetter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		user, err := userGetter.GetUser(ctx, c.Param("email"))
+		if err != nil {
+			return a.HandleError(c, err, errors.CodeFrom(err))
+		}
+
+		reviews := user.Reviews
+		if reviews == nil {
+			reviews = []model.Review{}
+		}
+		return HandleSuccess(c, map[string]interface{}{
+			"status":  review.StatusOf(*user),
+			"reviews": reviews,
+		}, http.StatusOK)
+	}
+}
+
+// GetAudit returns every recorded change to a volunteer, oldest first.
+func (a *AdminController) GetAudit(userGetter repository.UserGetter, auditStore repository.AuditStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		email := c.Param("email")
+		entries, err := auditStore.ListAudit(ctx, email)
+		if err != nil {
+			return a.HandleError(c, err, errors.CodeFrom(err))
+		}
+		if len(entries) == 0 {
+			// tell unknown volunteers apart from those never changed
+			if _, err := userGetter.GetUser(ctx, email); err != nil {
+				return a.HandleError(c, err, errors.CodeFrom(err))
+			}
+			entries = []model.AuditEntry{}
+		}
+
+		return HandleSuccess(c, entries, http.StatusOK)
+	}
+}
+
+func (a *AdminController) logAction(c echo.Context, action, email string) {
+	admin, _ := auth.AdminFrom(c)
+	a.logger.Info().Msgf("Admin: %s (%s) %s user %s", admin.Name, admin.Role, action, email)
+}
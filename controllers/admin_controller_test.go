@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+
+	"github.com/Reskill-2022/volunteering/model"
+	"github.com/Reskill-2022/volunteering/repository"
+	"github.com/Reskill-2022/volunteering/taxonomy"
+)
+
+func TestAdminUpdateUserAnswers(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryUserRepository(zerolog.Nop())
+	_, _, err := repo.CreateUser(ctx, model.User{
+		Email:          "jane@example.com",
+		State:          "Texas",
+		VolunteerAreas: model.StringList{"Mentoring"},
+		Industries:     model.StringList{"Finance"},
+		Answers: map[string]interface{}{
+			"state":           "Texas",
+			"volunteer_areas": []string{"Mentoring"},
+			"industries":      "Finance",
+			"organization":    "Acme",
+		},
+		FormVersion: 1,
+	})
+	if err != nil {
+		t.Fatalf("failed to seed user: %v", err)
+	}
+
+	body := `{"state": "Ohio", "volunteer_areas": ["Mentoring", "Career Coaching"], "industries": ["Finance", "Health Care"], "organization": ""}`
+	req := httptest.NewRequest(http.MethodPatch, "/admin/users/jane@example.com", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.SetParamNames("email")
+	c.SetParamValues("jane@example.com")
+
+	handler := NewAdminController(zerolog.Nop()).UpdateUser(repo, repo, taxonomy.NewCatalog(repo))
+	if err := handler(c); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("UpdateUser = %v, %d %s", err, rec.Code, rec.Body)
+	}
+
+	user, err := repo.GetUser(ctx, "jane@example.com")
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	answers := user.Answers
+	if answers["state"] != "Ohio" || user.State != "Ohio" {
+		t.Errorf("state answer %v, column %s, want Ohio", answers["state"], user.State)
+	}
+	if areas, _ := answers["volunteer_areas"].([]string); strings.Join(areas, ",") != "Mentoring,Career Coaching" {
+		t.Errorf("unexpected volunteer_areas answer %v", answers["volunteer_areas"])
+	}
+	if answers["industries"] != "Finance, Health Care" {
+		t.Errorf("unexpected industries answer %v", answers["industries"])
+	}
+	if _, ok := answers["organization"]; ok {
+		t.Errorf("expected the cleared organization answer to be removed, got %v", answers)
+	}
+}
+
+// slowLister takes longer to list users than the server may write.
+type slowLister struct {
+	repository.UserLister
+	delay time.Duration
+}
+
+func (l slowLister) ListUsers(ctx context.Context, query repository.UserQuery) (*repository.UserPage, error) {
+	time.Sleep(l.delay)
+	return l.UserLister.ListUsers(ctx, query)
+}
+
+func TestAdminExportUsersWriteTimeout(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(zerolog.Nop())
+	if _, _, err := repo.CreateUser(context.Background(), model.User{Email: "jane@example.com"}); err != nil {
+		t.Fatalf("failed to seed user: %v", err)
+	}
+
+	e := echo.New()
+	e.GET("/export", NewAdminController(zerolog.Nop()).ExportUsers(slowLister{repo, 100 * time.Millisecond}))
+	srv := httptest.NewUnstartedServer(e)
+	srv.Config.WriteTimeout = 50 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL + "/export?format=jsonl")
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("export = %d %s, %v", resp.StatusCode, body, err)
+	}
+	if !strings.Contains(string(body), "jane@example.com") {
+		t.Errorf("unexpected export %s", body)
+	}
+}
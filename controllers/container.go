@@ -3,11 +3,21 @@ package controllers
 import "github.com/rs/zerolog"
 
 type Container struct {
-	UserController *UserController
+	UserController     *UserController
+	AdminController    *AdminController
+	TaxonomyController *TaxonomyController
+	FormController     *FormController
+	JobController      *JobController
+	WebhookController  *WebhookController
 }
 
 func NewContainer(logger zerolog.Logger) *Container {
 	return &Container{
-		UserController: NewUserController(logger),
+		UserController:     NewUserController(logger),
+		AdminController:    NewAdminController(logger),
+		TaxonomyController: NewTaxonomyController(logger),
+		FormController:     NewFormController(logger),
+		JobController:      NewJobController(logger),
+		WebhookController:  NewWebhookController(logger),
 	}
 }
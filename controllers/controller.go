@@ -3,15 +3,20 @@ package controllers
 import (
 	"github.com/Reskill-2022/volunteering/errors"
 	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
 )
 
 func (u *UserController) HandleError(c echo.Context, err error, code int) error {
+	return handleError(u.logger, c, err, code)
+}
+
+func handleError(logger zerolog.Logger, c echo.Context, err error, code int) error {
 	if code < 100 {
 		code = 500
 	}
 
 	if code >= 500 {
-		u.logger.Err(err).Msg("internal error")
+		logger.Err(err).Msg("internal error")
 		return c.JSON(code, map[string]interface{}{
 			"error": "Internal Server Error. Something Bad Happened!",
 		})
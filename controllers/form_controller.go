@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+
+	"github.com/Reskill-2022/volunteering/auth"
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/forms"
+	"github.com/Reskill-2022/volunteering/requests"
+	"github.com/Reskill-2022/volunteering/taxonomy"
+)
+
+// FormController serves the application form definition and lets admins
+// publish new versions of it.
+type FormController struct {
+	logger zerolog.Logger
+}
+
+func NewFormController(logger zerolog.Logger) *FormController {
+	return &FormController{logger}
+}
+
+func (f *FormController) HandleError(c echo.Context, err error, code int) error {
+	return handleError(f.logger, c, err, code)
+}
+
+// GetForm returns the current form or, given a :version, that version, with
+// the options of option list fields filled in.
+func (f *FormController) GetForm(registry *forms.Registry, catalog *taxonomy.Catalog) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		version := 0
+		if param := c.Param("version"); param != "" {
+			v, err := strconv.Atoi(param)
+			if err != nil || v <= 0 {
+				return f.HandleError(c, errors.New("Invalid Form Version", 400), http.StatusBadRequest)
+			}
+			version = v
+		}
+
+		form, err := registry.Get(ctx, version)
+		if err != nil {
+			code := errors.CodeFrom(err)
+			if code == http.StatusBadRequest {
+				code = http.StatusNotFound
+			}
+			return f.HandleError(c, err, code)
+		}
+		options, err := catalog.Get(ctx)
+		if err != nil {
+			return f.HandleError(c, err, errors.CodeFrom(err))
+		}
+
+		return HandleSuccess(c, forms.Resolve(*form, options), http.StatusOK)
+	}
+}
+
+// CreateForm publishes the fields in the body as the next form version.
+// Applications already submitted keep the version they answered.
+func (f *FormController) CreateForm(registry *forms.Registry) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var requestBody requests.CreateFormRequest
+		if err := json.NewDecoder(c.Request().Body).Decode(&requestBody); err != nil {
+			return f.HandleError(c, errors.New("Invalid JSON Request Body", 400), http.StatusBadRequest)
+		}
+
+		admin, _ := auth.AdminFrom(c)
+		form, err := registry.Create(ctx, requestBody.Fields, admin.Name)
+		if err != nil {
+			return f.HandleError(c, err, errors.CodeFrom(err))
+		}
+
+		f.logger.Info().Msgf("Admin: %s (%s) published form version %d", admin.Name, admin.Role, form.Version)
+		return HandleSuccess(c, form, http.StatusCreated)
+	}
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+
+	"github.com/Reskill-2022/volunteering/auth"
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/jobs"
+)
+
+// JobController lets admins inspect the background job queue and retry
+// dead-lettered jobs.
+type JobController struct {
+	logger zerolog.Logger
+}
+
+func NewJobController(logger zerolog.Logger) *JobController {
+	return &JobController{logger}
+}
+
+func (j *JobController) HandleError(c echo.Context, err error, code int) error {
+	return handleError(j.logger, c, err, code)
+}
+
+// ListJobs returns the job counts and the ?state=pending (the default) or
+// dead jobs.
+func (j *JobController) ListJobs(queue *jobs.Queue) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		state := c.QueryParam("state")
+		if state == "" {
+			state = jobs.StatePending
+		}
+
+		list, err := queue.List(state)
+		if err != nil {
+			return j.HandleError(c, err, errors.CodeFrom(err))
+		}
+		stats, err := queue.Stats()
+		if err != nil {
+			return j.HandleError(c, err, errors.CodeFrom(err))
+		}
+
+		return HandleSuccess(c, map[string]interface{}{
+			"counts": stats,
+			"jobs":   list,
+		}, http.StatusOK)
+	}
+}
+
+func (j *JobController) RetryJob(queue *jobs.Queue) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return j.HandleError(c, errors.New("Invalid Job ID", 400), http.StatusBadRequest)
+		}
+
+		job, err := queue.Retry(id)
+		if err != nil {
+			return j.HandleError(c, err, errors.CodeFrom(err))
+		}
+
+		admin, _ := auth.AdminFrom(c)
+		j.logger.Info().Msgf("Admin: %s (%s) retried %s job %d", admin.Name, admin.Role, job.Kind, job.ID)
+		return HandleSuccess(c, job, http.StatusOK)
+	}
+}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+
+	"github.com/Reskill-2022/volunteering/auth"
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/model"
+	"github.com/Reskill-2022/volunteering/taxonomy"
+)
+
+// TaxonomyController serves the option lists the application form is
+// rendered from and lets admins replace them.
+type TaxonomyController struct {
+	logger zerolog.Logger
+}
+
+func NewTaxonomyController(logger zerolog.Logger) *TaxonomyController {
+	return &TaxonomyController{logger}
+}
+
+func (t *TaxonomyController) HandleError(c echo.Context, err error, code int) error {
+	return handleError(t.logger, c, err, code)
+}
+
+func (t *TaxonomyController) GetTaxonomy(catalog *taxonomy.Catalog) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		lists, err := catalog.Get(c.Request().Context())
+		if err != nil {
+			return t.HandleError(c, err, errors.CodeFrom(err))
+		}
+
+		return HandleSuccess(c, lists, http.StatusOK)
+	}
+}
+
+func (t *TaxonomyController) GetOptionList(catalog *taxonomy.Catalog) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		lists, err := catalog.Get(c.Request().Context())
+		if err != nil {
+			return t.HandleError(c, err, errors.CodeFrom(err))
+		}
+
+		options, ok := lists[c.Param("name")]
+		if !ok {
+			return t.HandleError(c, errors.New("Option List Not Found", 404), http.StatusNotFound)
+		}
+
+		return HandleSuccess(c, options, http.StatusOK)
+	}
+}
+
+// PutOptionList replaces an option list. Volunteers who already applied
+// keep their answers, even if they are no longer listed.
+func (t *TaxonomyController) PutOptionList(catalog *taxonomy.Catalog) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var options []model.Option
+		if err := json.NewDecoder(c.Request().Body).Decode(&options); err != nil {
+			return t.HandleError(c, errors.New("Invalid JSON Request Body", 400), http.StatusBadRequest)
+		}
+
+		name := c.Param("name")
+		if err := catalog.Put(ctx, name, options); err != nil {
+			return t.HandleError(c, err, errors.CodeFrom(err))
+		}
+
+		admin, _ := auth.AdminFrom(c)
+		t.logger.Info().Msgf("Admin: %s (%s) replaced option list %s with %d options", admin.Name, admin.Role, name, len(options))
+		return HandleSuccess(c, options, http.StatusOK)
+	}
+}
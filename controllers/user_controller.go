@@ -1,8 +1,10 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 	"time"
@@ -10,13 +12,23 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/rs/zerolog"
 
+	"github.com/Reskill-2022/volunteering/audit"
+	"github.com/Reskill-2022/volunteering/auth"
 	"github.com/Reskill-2022/volunteering/errors"
-	"github.com/Reskill-2022/volunteering/linkedin"
+	"github.com/Reskill-2022/volunteering/forms"
+	"github.com/Reskill-2022/volunteering/identity"
 	"github.com/Reskill-2022/volunteering/model"
+	"github.com/Reskill-2022/volunteering/notify"
 	"github.com/Reskill-2022/volunteering/repository"
 	"github.com/Reskill-2022/volunteering/requests"
+	"github.com/Reskill-2022/volunteering/review"
+	"github.com/Reskill-2022/volunteering/taxonomy"
+	"github.com/Reskill-2022/volunteering/validation"
+	"github.com/Reskill-2022/volunteering/webhooks"
 )
 
+var errDeactivated = errors.New("Account Deactivated. Please Contact the Volunteering Team", 403)
+
 type UserController struct {
 	logger zerolog.Logger
 }
@@ -25,7 +37,7 @@ func NewUserController(logger zerolog.Logger) *UserController {
 	return &UserController{logger}
 }
 
-func (u *UserController) CreateUser(userCreator repository.UserCreator, service linkedin.Service) echo.HandlerFunc {
+func (u *UserController) CreateUser(userCreator repository.UserCreator, providers identity.Providers, sessions auth.TokenIssuer, notifier *notify.Notifier, hooks *webhooks.Dispatcher) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		ctx := c.Request().Context()
 
@@ -45,12 +57,23 @@ func (u *UserController) CreateUser(userCreator repository.UserCreator, service
 			return u.HandleError(c, errors.New("Redirect URI is required", 400), http.StatusBadRequest)
 		}
 
-		fmt.Printf("Auth Code: %s, Redirect URI: %s", authCode, redirectURI)
+		providerName := requestBody.Provider
+		if providerName == "" {
+			providerName = identity.LinkedIn
+		}
+		provider, ok := providers[providerName]
+		if !ok {
+			msg := fmt.Sprintf("Unsupported Sign-In Provider. Use one of: %s", strings.Join(providers.Names(), ", "))
+			return u.HandleError(c, errors.New(msg, 400), http.StatusBadRequest)
+		}
+		displayName := identity.DisplayName(providerName)
+
+		u.logger.Debug().Msgf("Signing in with %s, redirect URI: %s", providerName, redirectURI)
 
-		profile, err := service.GetProfile(authCode, redirectURI)
+		profile, err := provider.GetProfile(authCode, redirectURI)
 		if err != nil {
 			u.logger.Err(err).Msg("Error getting profile")
-			return u.HandleError(c, errors.New("Failed to Validate LinkedIn Profile", 400), http.StatusBadRequest)
+			return u.HandleError(c, errors.New(fmt.Sprintf("Failed to Validate %s Profile", displayName), 400), http.StatusBadRequest)
 		}
 
 		// do validations
@@ -59,7 +82,7 @@ func (u *UserController) CreateUser(userCreator repository.UserCreator, service
 		}
 
 		if profile.Photo == "" {
-			return u.HandleError(c, errors.New("Invalid Profile. Please Set Your Profile Picture on LinkedIn", 400), http.StatusBadRequest)
+			return u.HandleError(c, errors.New(fmt.Sprintf("Invalid Profile. Please Set Your Profile Picture on %s", displayName), 400), http.StatusBadRequest)
 		}
 
 		firstname, lastname := u.splitNames(profile.Name)
@@ -71,97 +94,284 @@ func (u *UserController) CreateUser(userCreator repository.UserCreator, service
 			LastName:  lastname,
 			Phone:     profile.Phone,
 			Photo:     profile.Photo,
+			Provider:  providerName,
 			CreatedAt: time.Now().UTC(),
+
+			Location:      profile.Location,
+			HasExperience: profile.HasExperience,
+			Positions:     profile.Positions,
+		}
+		if ok, _ := isValidLinkedIn(profile.ProfileURL); ok {
+			data.ProfileURL, _ = validation.LinkedInURL(profile.ProfileURL)
 		}
 
-		user, err := userCreator.CreateUser(ctx, data)
+		ctx = audit.WithActor(ctx, audit.Actor{Name: data.Email, Role: audit.RoleVolunteer})
+		user, created, err := userCreator.CreateUser(ctx, data)
 		if err != nil {
 			return u.HandleError(c, err, errors.CodeFrom(err))
 		}
+		if user.Deactivated {
+			return u.HandleError(c, errDeactivated, http.StatusForbidden)
+		}
+		// returning volunteers get their existing record back
+		if created {
+			notifier.Notify(notify.AccountCreated, *user)
+			hooks.Emit(ctx, webhooks.VolunteerCreated, webhooks.VolunteerData(*user))
+		}
 
-		return HandleSuccess(c, user, http.StatusCreated)
+		token, expiresAt, err := sessions.Issue(user.Email)
+		if err != nil {
+			return u.HandleError(c, err, http.StatusInternalServerError)
+		}
+
+		return HandleSuccess(c, requests.SessionResponse{
+			User:           user,
+			Token:          token,
+			TokenExpiresAt: expiresAt,
+		}, http.StatusCreated)
 	}
 }
 
-func (u *UserController) UpdateUser(userGetter repository.UserGetter, userUpdater repository.UserUpdater) echo.HandlerFunc {
+// UpdateUser submits the application in one request. The body holds the
+// answers keyed by field name and, optionally, the form_version they answer.
+func (u *UserController) UpdateUser(userGetter repository.UserGetter, userUpdater repository.UserUpdater, registry *forms.Registry, catalog *taxonomy.Catalog, notifier *notify.Notifier, hooks *webhooks.Dispatcher) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		ctx := c.Request().Context()
 
-		var requestBody requests.UpdateUserRequest
+		var requestBody map[string]interface{}
 
 		err := json.NewDecoder(c.Request().Body).Decode(&requestBody)
 		if err != nil {
 			return u.HandleError(c, err, http.StatusBadRequest)
 		}
 
-		update, err := userGetter.GetUser(ctx, c.Param("email"))
+		update, err := u.applicant(ctx, userGetter, c.Param("email"))
 		if err != nil {
 			return u.HandleError(c, err, errors.CodeFrom(err))
 		}
-		if update.Enrolled {
-			return u.HandleError(c, errors.New("Responses already recorded. You have applied!", 400), http.StatusBadRequest)
+
+		version, answers, err := forms.Submission(requestBody)
+		if err != nil {
+			return u.HandleError(c, err, http.StatusBadRequest)
 		}
 
-		{
-			if requestBody.State == "" {
-				return u.HandleError(c, errors.New("Missing Field! State is required", 400), http.StatusBadRequest)
-			}
-			update.State = requestBody.State
+		user, err := u.submit(ctx, userUpdater, update, version, answers, registry, catalog)
+		if err != nil {
+			return u.HandleError(c, err, errors.CodeFrom(err))
+		}
+		notifier.Notify(notify.ApplicationReceived, *user)
+		hooks.Emit(ctx, webhooks.VolunteerEnrolled, webhooks.VolunteerData(*user))
 
-			if requestBody.Organization == "" {
-				return u.HandleError(c, errors.New("Missing Field! Organization is required", 400), http.StatusBadRequest)
-			}
-			update.Organization = requestBody.Organization
+		return HandleSuccess(c, user, http.StatusOK)
+	}
+}
 
-			if requestBody.YearsOfExperience == "" {
-				return u.HandleError(c, errors.New("Missing Field! Years of Experience is required", 400), http.StatusBadRequest)
-			}
-			update.YearsOfExperience = requestBody.YearsOfExperience
+// SaveDraft saves the answers in the body to the volunteer's draft. Only the
+// answers given are checked, and answers set to null are cleared.
+func (u *UserController) SaveDraft(userGetter repository.UserGetter, userUpdater repository.UserUpdater, registry *forms.Registry, catalog *taxonomy.Catalog) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
 
-			if requestBody.VolunteerAreas == nil {
-				return u.HandleError(c, errors.New("Missing Field! Volunteer Areas is required", 400), http.StatusBadRequest)
-			}
-			update.VolunteerAreas = strings.Join(requestBody.VolunteerAreas, ",")
+		var requestBody map[string]interface{}
 
-			if requestBody.VolunteerMeans == nil {
-				return u.HandleError(c, errors.New("Missing Field! Volunteer Means is required", 400), http.StatusBadRequest)
+		err := json.NewDecoder(c.Request().Body).Decode(&requestBody)
+		if err != nil {
+			return u.HandleError(c, errors.New("Invalid JSON Request Body", 400), http.StatusBadRequest)
+		}
+
+		update, err := u.applicant(ctx, userGetter, c.Param("email"))
+		if err != nil {
+			return u.HandleError(c, err, errors.CodeFrom(err))
+		}
+
+		version, answers, err := forms.Submission(requestBody)
+		if err != nil {
+			return u.HandleError(c, err, http.StatusBadRequest)
+		}
+
+		// build a new draft, the stored one is the audit log's before
+		draft := &model.Draft{FormVersion: version, Answers: make(map[string]interface{})}
+		if saved := update.Draft; saved != nil {
+			draft.FormVersion = saved.FormVersion
+			for name, value := range saved.Answers {
+				draft.Answers[name] = value
 			}
-			update.VolunteerMeans = strings.Join(requestBody.VolunteerMeans, ",")
+		}
+		if err := checkDraftVersion(draft, version); err != nil {
+			return u.HandleError(c, err, http.StatusConflict)
+		}
+
+		form, err := registry.Get(ctx, draft.FormVersion)
+		if err != nil {
+			return u.HandleError(c, err, errors.CodeFrom(err))
+		}
+		options, err := catalog.Get(ctx)
+		if err != nil {
+			return u.HandleError(c, err, errors.CodeFrom(err))
+		}
 
-			if requestBody.Convicted == nil {
-				return u.HandleError(c, errors.New("Missing Field! Convicted is required", 400), http.StatusBadRequest)
+		answers, err = forms.CheckPartial(*form, answers, options)
+		if err != nil {
+			return u.HandleError(c, err, http.StatusBadRequest)
+		}
+		for name, value := range answers {
+			if value == nil {
+				delete(draft.Answers, name)
+				continue
 			}
-			update.Convicted = *requestBody.Convicted
+			draft.Answers[name] = value
+		}
 
-			// if requestBody.WillJoinDirectory != nil {
-			// 	update.WillJoinDirectory = *requestBody.WillJoinDirectory
-			// }
+		draft.FormVersion = form.Version
+		draft.Steps = forms.Progress(*form, draft.Answers, options)
+		draft.UpdatedAt = time.Now().UTC()
+		update.Draft = draft
 
-			// if requestBody.SelfSummary != "" {
-			// 	update.SelfSummary = requestBody.SelfSummary
-			// }
+		user, err := userUpdater.UpdateUser(ctx, *update)
+		if err != nil {
+			return u.HandleError(c, err, errors.CodeFrom(err))
+		}
+
+		return HandleSuccess(c, user, http.StatusOK)
+	}
+}
 
-			if requestBody.Representation == "" {
-				return u.HandleError(c, errors.New("Missing Field! Representation is required", 400), http.StatusBadRequest)
+// SubmitApplication submits the volunteer's draft, together with any answers
+// in the body, and enrolls them.
+func (u *UserController) SubmitApplication(userGetter repository.UserGetter, userUpdater repository.UserUpdater, registry *forms.Registry, catalog *taxonomy.Catalog, notifier *notify.Notifier, hooks *webhooks.Dispatcher) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		requestBody := make(map[string]interface{})
+
+		// the body is optional
+		err := json.NewDecoder(c.Request().Body).Decode(&requestBody)
+		if err != nil && err != io.EOF {
+			return u.HandleError(c, errors.New("Invalid JSON Request Body", 400), http.StatusBadRequest)
+		}
+
+		update, err := u.applicant(ctx, userGetter, c.Param("email"))
+		if err != nil {
+			return u.HandleError(c, err, errors.CodeFrom(err))
+		}
+
+		version, answers, err := forms.Submission(requestBody)
+		if err != nil {
+			return u.HandleError(c, err, http.StatusBadRequest)
+		}
+
+		if draft := update.Draft; draft != nil {
+			if err := checkDraftVersion(draft, version); err != nil {
+				return u.HandleError(c, err, http.StatusConflict)
 			}
-			update.Representation = requestBody.Representation
+			version = draft.FormVersion
 
-			if requestBody.ProvidedName == "" {
-				return u.HandleError(c, errors.New("Missing Field! Name is required", 400), http.StatusBadRequest)
+			merged := make(map[string]interface{}, len(draft.Answers)+len(answers))
+			for name, value := range draft.Answers {
+				merged[name] = value
+			}
+			for name, value := range answers {
+				merged[name] = value
 			}
-			update.ProvidedName = requestBody.ProvidedName
+			answers = merged
+		}
+
+		user, err := u.submit(ctx, userUpdater, update, version, answers, registry, catalog)
+		if err != nil {
+			return u.HandleError(c, err, errors.CodeFrom(err))
+		}
+		notifier.Notify(notify.ApplicationReceived, *user)
+		hooks.Emit(ctx, webhooks.VolunteerEnrolled, webhooks.VolunteerData(*user))
+
+		return HandleSuccess(c, user, http.StatusOK)
+	}
+}
+
+// applicant returns the volunteer with email if they can still apply.
+func (u *UserController) applicant(ctx context.Context, userGetter repository.UserGetter, email string) (*model.User, error) {
+	user, err := userGetter.GetUser(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if user.Deactivated {
+		return nil, errDeactivated
+	}
+	if user.Enrolled {
+		return nil, errors.New("Responses already recorded. You have applied!", 400)
+	}
+	return user, nil
+}
+
+// submit checks answers against the form version as a whole, records them on
+// user and enrolls them. Any draft is discarded.
+func (u *UserController) submit(ctx context.Context, userUpdater repository.UserUpdater, user *model.User, version int, answers map[string]interface{}, registry *forms.Registry, catalog *taxonomy.Catalog) (*model.User, error) {
+	form, err := registry.Get(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+	options, err := catalog.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	answers, err = forms.Check(*form, answers, options)
+	if err != nil {
+		return nil, err
+	}
+	if err := forms.Apply(user, form.Version, answers); err != nil {
+		return nil, err
+	}
+
+	user.Enrolled = true
+	user.Draft = nil
+	review.Submit(user, time.Now().UTC())
+	return userUpdater.UpdateUser(ctx, *user)
+}
+
+// Withdraw withdraws the volunteer's application. The body, with a note for
+// the reviewers, is optional.
+func (u *UserController) Withdraw(userGetter repository.UserGetter, userUpdater repository.UserUpdater, hooks *webhooks.Dispatcher) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var requestBody requests.WithdrawRequest
+
+		err := json.NewDecoder(c.Request().Body).Decode(&requestBody)
+		if err != nil && err != io.EOF {
+			return u.HandleError(c, errors.New("Invalid JSON Request Body", 400), http.StatusBadRequest)
+		}
+
+		update, err := userGetter.GetUser(ctx, c.Param("email"))
+		if err != nil {
+			return u.HandleError(c, err, errors.CodeFrom(err))
+		}
+		if update.Deactivated {
+			return u.HandleError(c, errDeactivated, http.StatusForbidden)
+		}
+
+		err = review.Transition(update, model.StatusWithdrawn, update.Email, review.RoleVolunteer, requestBody.Note, time.Now().UTC())
+		if err != nil {
+			return u.HandleError(c, err, errors.CodeFrom(err))
 		}
 
-		update.Enrolled = true
 		user, err := userUpdater.UpdateUser(ctx, *update)
 		if err != nil {
 			return u.HandleError(c, err, errors.CodeFrom(err))
 		}
 
+		hooks.Emit(ctx, webhooks.StatusChanged, webhooks.StatusChangeData(*user))
+
 		return HandleSuccess(c, user, http.StatusOK)
 	}
 }
 
+func checkDraftVersion(draft *model.Draft, version int) error {
+	if version != 0 && draft.FormVersion != 0 && version != draft.FormVersion {
+		return errors.New(fmt.Sprintf("Your Draft Was Saved Against Form Version %d. Reload The Form To Continue", draft.FormVersion), 409)
+	}
+	return nil
+}
+
 func (u *UserController) GetUser(userGetter repository.UserGetter) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		ctx := c.Request().Context()
@@ -1,6 +1,17 @@
 package controllers
 
-import "testing"
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+
+	"github.com/Reskill-2022/volunteering/model"
+	"github.com/Reskill-2022/volunteering/repository"
+)
 
 func TestLinkedinURL(t *testing.T) {
 	testCases := []struct {
@@ -41,8 +52,36 @@ func TestIndustries(t *testing.T) {
 
 	for _, tc := range testCases {
 		err := validateIndustries(tc.industries)
-		if err != nil {
-			t.Errorf("validateIndustries(%s) returned unexpected error: %v", tc.industries, err)
+		if (err == nil) != tc.match {
+			t.Errorf("validateIndustries(%s) = %v, want valid %t", tc.industries, err, tc.match)
 		}
 	}
 }
+
+func TestWithdrawDeactivated(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryUserRepository(zerolog.Nop())
+	_, _, err := repo.CreateUser(ctx, model.User{Email: "jane@example.com", Enrolled: true, Status: model.StatusSubmitted, Deactivated: true})
+	if err != nil {
+		t.Fatalf("failed to seed user: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/users/jane@example.com/withdraw", nil)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.SetParamNames("email")
+	c.SetParamValues("jane@example.com")
+
+	handler := NewUserController(zerolog.Nop()).Withdraw(repo, repo, nil)
+	if err := handler(c); err != nil || rec.Code != http.StatusForbidden {
+		t.Fatalf("Withdraw = %v, %d %s, want %d", err, rec.Code, rec.Body, http.StatusForbidden)
+	}
+
+	user, err := repo.GetUser(ctx, "jane@example.com")
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	if user.Status != model.StatusSubmitted {
+		t.Errorf("deactivated user withdrew, status %s", user.Status)
+	}
+}
@@ -0,0 +1,16 @@
+package controllers
+
+import "github.com/Reskill-2022/volunteering/validation"
+
+// isValidLinkedIn reports whether profileURL links to a LinkedIn profile.
+// Profile URLs from other identity providers are not.
+func isValidLinkedIn(profileURL string) (bool, error) {
+	_, err := validation.LinkedInURL(profileURL)
+	return err == nil, nil
+}
+
+// validateIndustries checks a comma-separated list of industries.
+func validateIndustries(industries string) error {
+	_, err := validation.ParseIndustries(industries)
+	return err
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+
+	"github.com/Reskill-2022/volunteering/auth"
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/repository"
+	"github.com/Reskill-2022/volunteering/webhooks"
+)
+
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 200
+)
+
+// WebhookController lets admins inspect webhook subscriptions and deliveries,
+// and send deliveries again.
+type WebhookController struct {
+	logger zerolog.Logger
+}
+
+func NewWebhookController(logger zerolog.Logger) *WebhookController {
+	return &WebhookController{logger}
+}
+
+func (w *WebhookController) HandleError(c echo.Context, err error, code int) error {
+	return handleError(w.logger, c, err, code)
+}
+
+// ListWebhooks returns the configured webhooks, without their secrets.
+func (w *WebhookController) ListWebhooks(hooks *webhooks.Dispatcher) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return HandleSuccess(c, hooks.Webhooks(), http.StatusOK)
+	}
+}
+
+// ListDeliveries returns the latest deliveries, newest first, optionally of
+// one ?webhook= only.
+func (w *WebhookController) ListDeliveries(deliveryStore repository.DeliveryStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		limit := defaultDeliveryLimit
+		if raw := c.QueryParam("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 || n > maxDeliveryLimit {
+				return w.HandleError(c, errors.New("Limit must be between 1 and 200", 400), http.StatusBadRequest)
+			}
+			limit = n
+		}
+
+		deliveries, err := deliveryStore.ListDeliveries(ctx, c.QueryParam("webhook"), limit)
+		if err != nil {
+			return w.HandleError(c, err, errors.CodeFrom(err))
+		}
+
+		return HandleSuccess(c, deliveries, http.StatusOK)
+	}
+}
+
+func (w *WebhookController) GetDelivery(deliveryStore repository.DeliveryStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		delivery, err := deliveryStore.GetDelivery(ctx, c.Param("id"))
+		if err != nil {
+			return w.HandleError(c, err, errors.CodeFrom(err))
+		}
+
+		return HandleSuccess(c, delivery, http.StatusOK)
+	}
+}
+
+func (w *WebhookController) Redeliver(hooks *webhooks.Dispatcher) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		delivery, err := hooks.Redeliver(ctx, c.Param("id"))
+		if err != nil {
+			return w.HandleError(c, err, errors.CodeFrom(err))
+		}
+
+		admin, _ := auth.AdminFrom(c)
+		w.logger.Info().Msgf("Admin: %s (%s) redelivered %s to webhook %s", admin.Name, admin.Role, delivery.Event, delivery.Webhook)
+		return HandleSuccess(c, delivery, http.StatusAccepted)
+	}
+}
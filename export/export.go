@@ -0,0 +1,238 @@
+// Package export writes volunteers as CSV, XLSX or JSON Lines, streaming them
+// page by page from a repository.UserLister.
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/model"
+	"github.com/Reskill-2022/volunteering/repository"
+)
+
+const (
+	CSV   = "csv"
+	XLSX  = "xlsx"
+	JSONL = "jsonl"
+)
+
+// Formats lists the supported formats.
+var Formats = []string{CSV, XLSX, JSONL}
+
+var contentTypes = map[string]string{
+	CSV:   "text/csv; charset=utf-8",
+	XLSX:  "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	JSONL: "application/x-ndjson",
+}
+
+// baseColumns are the spreadsheet columns before the volunteer area and means
+// columns.
+var baseColumns = []string{
+	"Email", "Name", "First Name", "Last Name", "Phone", "Provider",
+	"Location", "Has Experience", "Positions",
+	"State", "Organization", "Years of Experience", "Convicted",
+	"Representation", "Provided Name", "Profile URL", "Industries",
+	"Enrolled", "Status", "Submitted At", "Deactivated", "Created At",
+}
+
+type Options struct {
+	Format string
+	// Query filters and sorts the exported users. Its Limit and Cursor are
+	// ignored.
+	Query repository.UserQuery
+	// Areas and Means are given one column each in CSV and XLSX exports.
+	// When nil, they are collected from the exported users first, which
+	// reads them twice.
+	Areas []string
+	Means []string
+}
+
+// rowWriter writes a spreadsheet one row at a time.
+type rowWriter interface {
+	Write(row []string) error
+	Flush() error
+	Close() error
+}
+
+// ContentType returns the MIME type of format.
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// ValidFormat returns a 400 error unless format is supported.
+func ValidFormat(format string) error {
+	if _, ok := contentTypes[format]; !ok {
+		return errors.New(fmt.Sprintf("Unsupported Export Format. Use one of: %s", strings.Join(Formats, ", ")), 400)
+	}
+	return nil
+}
+
+// Users writes every user matching opts.Query to w and returns how many were
+// written. If w has a Flush method, it is called after every page so that
+// HTTP responses are streamed.
+func Users(ctx context.Context, w io.Writer, lister repository.UserLister, opts Options) (int, error) {
+	if err := ValidFormat(opts.Format); err != nil {
+		return 0, err
+	}
+	if err := opts.Query.Validate(); err != nil {
+		return 0, err
+	}
+
+	if opts.Format == JSONL {
+		enc := newJSONL(w)
+		return each(ctx, lister, opts.Query, w, enc.Write, nil)
+	}
+
+	areas, means := opts.Areas, opts.Means
+	if areas == nil || means == nil {
+		var err error
+		if areas, means, err = collect(ctx, lister, opts.Query); err != nil {
+			return 0, err
+		}
+	}
+
+	var rw rowWriter
+	if opts.Format == XLSX {
+		rw = newXLSX(w)
+	} else {
+		rw = newCSV(w)
+	}
+
+	header := append([]string{}, baseColumns...)
+	for _, a := range areas {
+		header = append(header, "Area: "+a)
+	}
+	for _, m := range means {
+		header = append(header, "Means: "+m)
+	}
+	if err := rw.Write(header); err != nil {
+		return 0, err
+	}
+
+	n, err := each(ctx, lister, opts.Query, w, func(u model.User) error {
+		return rw.Write(row(u, areas, means))
+	}, rw.Flush)
+	if err != nil {
+		return n, err
+	}
+	return n, rw.Close()
+}
+
+// each calls fn for every user matching query, flushing after every page.
+func each(ctx context.Context, lister repository.UserLister, query repository.UserQuery, w io.Writer, fn func(model.User) error, flush func() error) (int, error) {
+	query.Limit = repository.MaxPageSize
+	query.Cursor = ""
+
+	n := 0
+	for {
+		page, err := lister.ListUsers(ctx, query)
+		if err != nil {
+			return n, err
+		}
+		for _, u := range page.Users {
+			if err := fn(u); err != nil {
+				return n, err
+			}
+			n++
+		}
+
+		if flush != nil {
+			if err := flush(); err != nil {
+				return n, err
+			}
+		}
+		if f, ok := w.(interface{ Flush() }); ok {
+			f.Flush()
+		}
+
+		if page.NextCursor == "" {
+			return n, nil
+		}
+		query.Cursor = page.NextCursor
+	}
+}
+
+// collect returns every distinct volunteer area and means of the users
+// matching query, sorted and compared case-insensitively.
+func collect(ctx context.Context, lister repository.UserLister, query repository.UserQuery) ([]string, []string, error) {
+	areas, means := map[string]string{}, map[string]string{}
+	add := func(seen map[string]string, values []string) {
+		for _, v := range values {
+			if _, ok := seen[strings.ToLower(v)]; !ok {
+				seen[strings.ToLower(v)] = v
+			}
+		}
+	}
+
+	_, err := each(ctx, lister, query, io.Discard, func(u model.User) error {
+		add(areas, u.VolunteerAreas)
+		add(means, u.VolunteerMeans)
+		return nil
+	}, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sorted(areas), sorted(means), nil
+}
+
+func sorted(seen map[string]string) []string {
+	values := make([]string, 0, len(seen))
+	for _, v := range seen {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool { return strings.ToLower(values[i]) < strings.ToLower(values[j]) })
+	return values
+}
+
+func row(u model.User, areas, means []string) []string {
+	r := []string{
+		u.Email, u.Name, u.FirstName, u.LastName, u.Phone, u.Provider,
+		u.Location, yesNo(u.HasExperience), strings.Join(u.Positions, "; "),
+		u.State, u.Organization, u.YearsOfExperience, yesNo(u.Convicted),
+		u.Representation, u.ProvidedName, u.ProfileURL, strings.Join(u.Industries, ", "),
+		yesNo(u.Enrolled), u.Status, submittedAt(u), yesNo(u.Deactivated),
+		createdAt(u),
+	}
+	r = append(r, marks(u.VolunteerAreas, areas)...)
+	return append(r, marks(u.VolunteerMeans, means)...)
+}
+
+// marks returns "Yes" in the position of every column in values.
+func marks(values, columns []string) []string {
+	out := make([]string, len(columns))
+	for i, c := range columns {
+		for _, v := range values {
+			if strings.EqualFold(v, c) {
+				out[i] = "Yes"
+				break
+			}
+		}
+	}
+	return out
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "Yes"
+	}
+	return "No"
+}
+
+func submittedAt(u model.User) string {
+	if u.SubmittedAt == nil {
+		return ""
+	}
+	return u.SubmittedAt.UTC().Format(time.RFC3339)
+}
+
+func createdAt(u model.User) string {
+	if u.CreatedAt.IsZero() {
+		return ""
+	}
+	return u.CreatedAt.UTC().Format(time.RFC3339)
+}
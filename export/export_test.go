@@ -0,0 +1,185 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Reskill-2022/volunteering/model"
+	"github.com/Reskill-2022/volunteering/repository"
+)
+
+func testRepository(t *testing.T, n int) *repository.MemoryUserRepository {
+	t.Helper()
+
+	repo := repository.NewMemoryUserRepository(zerolog.Nop())
+	for i := 0; i < n; i++ {
+		user := model.User{
+			Email:          fmt.Sprintf("user%03d@example.com", i),
+			Name:           fmt.Sprintf("User <%d> & co", i),
+			State:          "Texas",
+			VolunteerAreas: model.StringList{"Mentoring", "Design"},
+			VolunteerMeans: model.StringList{"Remote"},
+			Enrolled:       i%2 == 0,
+			CreatedAt:      time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC),
+		}
+		if i == 0 {
+			user.VolunteerAreas = model.StringList{"design", "Research"}
+		}
+		if _, _, err := repo.CreateUser(context.Background(), user); err != nil {
+			t.Fatalf("failed to seed users: %v", err)
+		}
+	}
+	return repo
+}
+
+func TestUsersCSV(t *testing.T) {
+	repo := testRepository(t, repository.MaxPageSize+5)
+	enrolled := true
+
+	var buf bytes.Buffer
+	n, err := Users(context.Background(), &buf, repo, Options{
+		Format: CSV,
+		Query:  repository.UserQuery{Filter: repository.UserFilter{Enrolled: &enrolled}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read csv: %v", err)
+	}
+	if n != 103 || len(records) != n+1 {
+		t.Fatalf("expected 103 users and a header, got %d users and %d records", n, len(records))
+	}
+
+	header := strings.Join(records[0][len(baseColumns):], "|")
+	if header != "Area: design|Area: Mentoring|Area: Research|Means: Remote" {
+		t.Errorf("unexpected area and means columns: %s", header)
+	}
+	if got := strings.Join(records[1][len(baseColumns):], "|"); got != "Yes||Yes|Yes" {
+		t.Errorf("unexpected marks for first user: %s", got)
+	}
+	if got := strings.Join(records[2][len(baseColumns):], "|"); got != "Yes|Yes||Yes" {
+		t.Errorf("unexpected marks for second user: %s", got)
+	}
+}
+
+func TestUsersXLSX(t *testing.T) {
+	repo := testRepository(t, 3)
+
+	var buf bytes.Buffer
+	if _, err := Users(context.Background(), &buf, repo, Options{Format: XLSX, Areas: []string{"Mentoring"}, Means: []string{}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("export is not a zip archive: %v", err)
+	}
+
+	var sheet string
+	for _, f := range zr.File {
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatalf("failed to open sheet: %v", err)
+			}
+			raw, _ := io.ReadAll(rc)
+			rc.Close()
+			sheet = string(raw)
+		}
+	}
+
+	if got := strings.Count(sheet, "<row>"); got != 4 {
+		t.Errorf("expected 4 rows, got %d", got)
+	}
+	if !strings.Contains(sheet, "User &lt;1&gt; &amp; co") {
+		t.Errorf("expected names to be escaped")
+	}
+	if !strings.HasSuffix(sheet, "</sheetData></worksheet>") {
+		t.Errorf("sheet is not terminated")
+	}
+}
+
+func TestUsersJSONL(t *testing.T) {
+	repo := testRepository(t, 2)
+
+	var buf bytes.Buffer
+	if _, err := Users(context.Background(), &buf, repo, Options{Format: JSONL}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+
+	var record struct {
+		Email          string   `json:"email"`
+		VolunteerAreas []string `json:"volunteer_areas"`
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil {
+		t.Fatalf("invalid json line: %v", err)
+	}
+	if record.Email != "user001@example.com" || fmt.Sprint(record.VolunteerAreas) != "[Mentoring Design]" {
+		t.Errorf("unexpected record: %+v", record)
+	}
+}
+
+func TestUsersInvalidFormat(t *testing.T) {
+	if _, err := Users(context.Background(), io.Discard, testRepository(t, 1), Options{Format: "pdf"}); err == nil {
+		t.Errorf("expected error for unsupported format")
+	}
+}
+
+func TestUsersCSVFormulas(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(zerolog.Nop())
+	_, _, err := repo.CreateUser(context.Background(), model.User{
+		Email:        "eve@example.com",
+		Name:         `=HYPERLINK("http://evil.example.com")`,
+		Phone:        "+1 512 555 0100",
+		Organization: "-Acme",
+		ProvidedName: "@Eve",
+		State:        "Texas",
+	})
+	if err != nil {
+		t.Fatalf("failed to seed users: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := Users(context.Background(), &buf, repo, Options{Format: CSV}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read csv: %v", err)
+	}
+
+	cells := make(map[string]string)
+	for i, column := range records[0] {
+		cells[column] = records[1][i]
+	}
+	want := map[string]string{
+		"Name":          `'=HYPERLINK("http://evil.example.com")`,
+		"Phone":         "'+1 512 555 0100",
+		"Organization":  "'-Acme",
+		"Provided Name": "'@Eve",
+		"State":         "Texas",
+	}
+	for column, value := range want {
+		if cells[column] != value {
+			t.Errorf("%s = %q, want %q", column, cells[column], value)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package forms
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/model"
+	"github.com/Reskill-2022/volunteering/taxonomy"
+	"github.com/Reskill-2022/volunteering/validation"
+)
+
+// versionKey is the key of a submission naming its form version. Clients
+// that omit it are answering the current form.
+const versionKey = "form_version"
+
+// columns are the answers also stored as model.User fields, which the admin
+// listing filters on, with the field types that fit them. A form may leave
+// any of them out.
+var columns = map[string][]string{
+	"state":               {model.FieldSelect, model.FieldText},
+	"organization":        {model.FieldText, model.FieldSelect},
+	"years_of_experience": {model.FieldSelect, model.FieldText},
+	"volunteer_areas":     {model.FieldMultiselect},
+	"volunteer_means":     {model.FieldMultiselect},
+	"convicted":           {model.FieldBoolean},
+	"representation":      {model.FieldSelect, model.FieldText},
+	"provided_name":       {model.FieldText},
+	"profile_url":         {model.FieldText},
+	"industries":          {model.FieldText, model.FieldTextarea},
+}
+
+// columnChecks validate and normalise the text answers of columns that have
+// a format of their own.
+var columnChecks = map[string]func(string) (string, error){
+	"profile_url": validation.LinkedInURL,
+	"industries": func(s string) (string, error) {
+		industries, err := validation.ParseIndustries(s)
+		return strings.Join(industries, ", "), err
+	},
+}
+
+// Submission splits a request body into the form version it was filled in
+// from and its answers.
+func Submission(body map[string]interface{}) (int, map[string]interface{}, error) {
+	raw, ok := body[versionKey]
+	delete(body, versionKey)
+	if !ok || raw == nil {
+		return 0, body, nil
+	}
+
+	version, ok := raw.(float64)
+	if !ok || version != math.Trunc(version) {
+		return 0, nil, errors.New("Invalid Form Version", 400)
+	}
+	return int(version), body, nil
+}
+
+// Check validates answers against form and returns them normalised: text is
+// trimmed, multiselect answers are lists of strings, and answers to hidden
+// or unknown questions are dropped. Choices are checked against lists for
+// fields that use an option list.
+func Check(form model.Form, answers map[string]interface{}, lists taxonomy.Taxonomy) (map[string]interface{}, error) {
+	checked := make(map[string]interface{}, len(form.Fields))
+	for _, f := range form.Fields {
+		if f.ShowIf != nil && !shown(*f.ShowIf, checked) {
+			continue
+		}
+
+		value, err := checkField(f, answers[f.Name], lists)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			if f.Required {
+				return nil, errors.New(fmt.Sprintf("Missing Field! %s is required", f.Label), 400)
+			}
+			continue
+		}
+		checked[f.Name] = value
+	}
+	return checked, nil
+}
+
+// CheckPartial validates only the answers given, as a draft is saved. It
+// returns them normalised, with nil for answers that were cleared. Required
+// and conditional questions are left to Check.
+func CheckPartial(form model.Form, answers map[string]interface{}, lists taxonomy.Taxonomy) (map[string]interface{}, error) {
+	checked := make(map[string]interface{}, len(answers))
+	for _, f := range form.Fields {
+		raw, ok := answers[f.Name]
+		if !ok {
+			continue
+		}
+
+		value, err := checkField(f, raw, lists)
+		if err != nil {
+			return nil, err
+		}
+		checked[f.Name] = value
+	}
+	return checked, nil
+}
+
+// Progress reports for every step of form whether answers complete it, that
+// is whether each of its required and shown questions has a valid answer.
+func Progress(form model.Form, answers map[string]interface{}, lists taxonomy.Taxonomy) map[string]bool {
+	steps := make(map[string]bool)
+	checked := make(map[string]interface{}, len(form.Fields))
+	for _, f := range form.Fields {
+		step := stepOf(f)
+		if _, ok := steps[step]; !ok {
+			steps[step] = true
+		}
+		if f.ShowIf != nil && !shown(*f.ShowIf, checked) {
+			continue
+		}
+
+		value, err := checkField(f, answers[f.Name], lists)
+		if err != nil || (value == nil && f.Required) {
+			steps[step] = false
+			continue
+		}
+		if value != nil {
+			checked[f.Name] = value
+		}
+	}
+	return steps
+}
+
+// checkField validates and normalises one answer, returning nil if it is
+// unanswered.
+func checkField(f model.Field, raw interface{}, lists taxonomy.Taxonomy) (interface{}, error) {
+	value, err := normalise(f, raw)
+	if err != nil || value == nil {
+		return nil, err
+	}
+
+	if check, ok := columnChecks[f.Name]; ok {
+		if value, err = check(value.(string)); err != nil {
+			return nil, err
+		}
+	}
+
+	options := taxonomy.Taxonomy{f.Name: f.Options}
+	if f.OptionList != "" {
+		options[f.Name] = lists[f.OptionList]
+	}
+	switch v := value.(type) {
+	case string:
+		if f.Type == model.FieldSelect {
+			err = options.Check(f.Name, f.Label, v)
+		}
+	case []string:
+		err = options.Check(f.Name, f.Label, v...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// normalise returns the answer to f, or nil if it is unanswered.
+func normalise(f model.Field, raw interface{}) (interface{}, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	invalid := errors.New(fmt.Sprintf("Invalid Answer! %s must be %s", f.Label, describe(f.Type)), 400)
+
+	switch f.Type {
+	case model.FieldBoolean:
+		if _, ok := raw.(bool); !ok {
+			return nil, invalid
+		}
+		return raw, nil
+
+	case model.FieldNumber:
+		if _, ok := raw.(float64); !ok {
+			return nil, invalid
+		}
+		return raw, nil
+
+	case model.FieldMultiselect:
+		// older clients send comma-separated strings
+		if s, ok := raw.(string); ok {
+			return []string(model.ParseStringList(s)), nil
+		}
+		// as saved in a draft
+		if values, ok := raw.([]string); ok {
+			return values, nil
+		}
+		items, ok := raw.([]interface{})
+		if !ok {
+			return nil, invalid
+		}
+		values := make([]string, 0, len(items))
+		for _, item := range items {
+			s, ok := item.(string)
+			if !ok {
+				return nil, invalid
+			}
+			if s = strings.TrimSpace(s); s != "" {
+				values = append(values, s)
+			}
+		}
+		return values, nil
+
+	default:
+		s, ok := raw.(string)
+		if !ok {
+			return nil, invalid
+		}
+		if s = strings.TrimSpace(s); s == "" {
+			return nil, nil
+		}
+		return s, nil
+	}
+}
+
+func describe(fieldType string) string {
+	switch fieldType {
+	case model.FieldBoolean:
+		return "true or false"
+	case model.FieldNumber:
+		return "a number"
+	case model.FieldMultiselect:
+		return "a list of choices"
+	default:
+		return "text"
+	}
+}
+
+func shown(c model.Condition, answers map[string]interface{}) bool {
+	var values []string
+	switch v := answers[c.Field].(type) {
+	case string:
+		values = []string{v}
+	case bool:
+		values = []string{strconv.FormatBool(v)}
+	case float64:
+		values = []string{strconv.FormatFloat(v, 'f', -1, 64)}
+	case []string:
+		values = v
+	}
+
+	for _, v := range values {
+		for _, want := range c.Values {
+			if v == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Apply stores checked answers of version on user, copying those that are
+// also user fields.
+func Apply(user *model.User, version int, answers map[string]interface{}) error {
+	copied := make(map[string]interface{})
+	for name := range columns {
+		if v, ok := answers[name]; ok {
+			copied[name] = v
+		}
+	}
+	raw, err := json.Marshal(copied)
+	if err != nil {
+		return errors.From(err, "failed to encode answers", 500)
+	}
+	if err := json.Unmarshal(raw, user); err != nil {
+		return errors.From(err, fmt.Sprintf("Invalid Answers for Form Version %d", version), 400)
+	}
+
+	user.Answers = answers
+	user.FormVersion = version
+	return nil
+}
+
+// Sync copies the named user fields, edited directly, back into the answers
+// they are copied from, so that the two agree. Cleared fields are removed
+// from the answers. Users who applied before answers were stored are left
+// alone.
+func Sync(user *model.User, names ...string) error {
+	if user.Answers == nil {
+		return nil
+	}
+	raw, err := json.Marshal(user)
+	if err != nil {
+		return errors.From(err, "failed to encode user", 500)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return errors.From(err, "failed to decode user", 500)
+	}
+
+	for _, name := range names {
+		types, ok := columns[name]
+		if !ok {
+			continue
+		}
+
+		var value interface{}
+		switch v := fields[name].(type) {
+		case string:
+			if v != "" {
+				value = v
+			}
+		case bool:
+			value = v
+		case []interface{}:
+			values := make([]string, 0, len(v))
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					values = append(values, s)
+				}
+			}
+			switch {
+			case len(values) == 0:
+			case contains(types, model.FieldMultiselect):
+				value = values
+			default:
+				// a list answered as text, such as the industries
+				value = strings.Join(values, ", ")
+			}
+		}
+
+		if value == nil {
+			delete(user.Answers, name)
+			continue
+		}
+		user.Answers[name] = value
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package forms
+
+import (
+	"github.com/Reskill-2022/volunteering/model"
+	"github.com/Reskill-2022/volunteering/taxonomy"
+)
+
+// DefaultVersion is the version of Default. Saved forms start after it.
+const DefaultVersion = 1
+
+// Default returns the form asked before forms could be edited, with the
+// optional LinkedIn profile and industries questions.
+func Default() *model.Form {
+	return &model.Form{
+		Version: DefaultVersion,
+		Fields: []model.Field{
+			{Name: "state", Label: "State", Type: model.FieldSelect, Required: true, OptionList: taxonomy.States, Step: "about"},
+			{Name: "organization", Label: "Organization", Type: model.FieldText, Required: true, Step: "about"},
+			{Name: "years_of_experience", Label: "Years of Experience", Type: model.FieldSelect, Required: true, OptionList: taxonomy.Experience, Step: "about"},
+			{Name: "volunteer_areas", Label: "Volunteer Areas", Type: model.FieldMultiselect, Required: true, OptionList: taxonomy.Areas, Step: "volunteering"},
+			{Name: "volunteer_means", Label: "Volunteer Means", Type: model.FieldMultiselect, Required: true, OptionList: taxonomy.Means, Step: "volunteering"},
+			{Name: "convicted", Label: "Convicted", Type: model.FieldBoolean, Required: true, Step: "declarations"},
+			{Name: "representation", Label: "Representation", Type: model.FieldSelect, Required: true, OptionList: taxonomy.Representation, Step: "volunteering"},
+			{Name: "provided_name", Label: "Name", Type: model.FieldText, Required: true, Step: "about"},
+			{Name: "profile_url", Label: "LinkedIn Profile", Type: model.FieldText, Step: "about"},
+			{Name: "industries", Label: "Industries", Type: model.FieldText, Help: "Separate industries with commas", Step: "about"},
+		},
+	}
+}
@@ -0,0 +1,225 @@
+// Package forms defines the application form as data. Each saved Form is a
+// new version; submissions are validated against the version they were
+// filled in from and stored with it.
+package forms
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/model"
+	"github.com/Reskill-2022/volunteering/repository"
+	"github.com/Reskill-2022/volunteering/taxonomy"
+)
+
+// cacheTTL bounds how long a form saved by another instance takes to become
+// the current one. Saved versions never change and are cached for good.
+const cacheTTL = time.Minute
+
+var errUnknownVersion = errors.New("Unknown Form Version", 400)
+
+// Registry serves form versions from a repository.FormStore, falling back to
+// Default while no form has been saved.
+type Registry struct {
+	store repository.FormStore
+
+	mu        sync.Mutex
+	versions  map[int]model.Form
+	current   int
+	fetchedAt time.Time
+}
+
+func NewRegistry(store repository.FormStore) *Registry {
+	return &Registry{store: store, versions: make(map[int]model.Form)}
+}
+
+// Current returns the latest form.
+func (r *Registry) Current(ctx context.Context) (*model.Form, error) {
+	r.mu.Lock()
+	if r.current != 0 && time.Since(r.fetchedAt) < cacheTTL {
+		form := r.versions[r.current]
+		r.mu.Unlock()
+		return &form, nil
+	}
+	r.mu.Unlock()
+
+	form, err := r.store.LatestForm(ctx)
+	if errors.CodeFrom(err) == 404 {
+		form, err = Default(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.versions[form.Version] = *form
+	r.current, r.fetchedAt = form.Version, time.Now()
+	r.mu.Unlock()
+	return form, nil
+}
+
+// Get returns a version of the form, or the current one for version 0.
+func (r *Registry) Get(ctx context.Context, version int) (*model.Form, error) {
+	if version == 0 {
+		return r.Current(ctx)
+	}
+	if version < 0 {
+		return nil, errUnknownVersion
+	}
+
+	r.mu.Lock()
+	form, ok := r.versions[version]
+	r.mu.Unlock()
+	if ok {
+		return &form, nil
+	}
+
+	got, err := r.store.GetForm(ctx, version)
+	if errors.CodeFrom(err) == 404 {
+		if version != DefaultVersion {
+			return nil, errUnknownVersion
+		}
+		got, err = Default(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.versions[version] = *got
+	r.mu.Unlock()
+	return got, nil
+}
+
+// Create validates fields and saves them as the next version of the form.
+func (r *Registry) Create(ctx context.Context, fields []model.Field, createdBy string) (*model.Form, error) {
+	if err := Validate(fields); err != nil {
+		return nil, err
+	}
+
+	// skip the cache, another instance may have saved a version
+	latest, err := r.store.LatestForm(ctx)
+	if errors.CodeFrom(err) == 404 {
+		latest, err = Default(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	form := model.Form{
+		Version:   latest.Version + 1,
+		Fields:    fields,
+		CreatedAt: time.Now().UTC(),
+		CreatedBy: createdBy,
+	}
+	if err := r.store.CreateForm(ctx, form); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.versions[form.Version] = form
+	r.current, r.fetchedAt = form.Version, time.Now()
+	r.mu.Unlock()
+	return &form, nil
+}
+
+// Resolve returns a copy of form with the options of every field that uses
+// an option list filled in, as the frontend renders it.
+func Resolve(form model.Form, lists taxonomy.Taxonomy) model.Form {
+	fields := make([]model.Field, len(form.Fields))
+	for i, f := range form.Fields {
+		if f.OptionList != "" {
+			f.Options = lists[f.OptionList]
+		}
+		fields[i] = f
+	}
+	form.Fields = fields
+	return form
+}
+
+// DefaultStep is the step of fields that name none.
+const DefaultStep = "application"
+
+// Steps returns the steps of form in the order they are first asked.
+func Steps(form model.Form) []string {
+	var steps []string
+	seen := make(map[string]bool)
+	for _, f := range form.Fields {
+		if step := stepOf(f); !seen[step] {
+			seen[step] = true
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+func stepOf(f model.Field) string {
+	if f.Step == "" {
+		return DefaultStep
+	}
+	return f.Step
+}
+
+// Validate checks that fields make a usable form.
+func Validate(fields []model.Field) error {
+	if len(fields) == 0 {
+		return errors.New("A Form needs at least one field", 400)
+	}
+
+	types := map[string]bool{
+		model.FieldText: true, model.FieldTextarea: true, model.FieldBoolean: true,
+		model.FieldNumber: true, model.FieldSelect: true, model.FieldMultiselect: true,
+	}
+	lists := make(map[string]bool)
+	for _, name := range taxonomy.Names {
+		lists[name] = true
+	}
+
+	seen := make(map[string]bool)
+	for i, f := range fields {
+		switch {
+		case strings.TrimSpace(f.Name) == "" || strings.TrimSpace(f.Label) == "":
+			return errors.New(fmt.Sprintf("Field %d needs a name and a label", i+1), 400)
+		case f.Name == versionKey:
+			return errors.New(fmt.Sprintf("Field name '%s' is reserved", f.Name), 400)
+		case seen[f.Name]:
+			return errors.New(fmt.Sprintf("Field '%s' is listed more than once", f.Name), 400)
+		case !types[f.Type]:
+			return errors.New(fmt.Sprintf("Field '%s' has unknown type '%s'", f.Name, f.Type), 400)
+		}
+
+		choice := f.Type == model.FieldSelect || f.Type == model.FieldMultiselect
+		switch {
+		case choice && f.OptionList != "" && len(f.Options) > 0:
+			return errors.New(fmt.Sprintf("Field '%s' must have either options or an option list", f.Name), 400)
+		case choice && f.OptionList != "" && !lists[f.OptionList]:
+			return errors.New(fmt.Sprintf("Field '%s' uses unknown option list '%s'", f.Name, f.OptionList), 400)
+		case choice && f.OptionList == "" && len(f.Options) == 0:
+			return errors.New(fmt.Sprintf("Field '%s' needs options", f.Name), 400)
+		case !choice && (f.OptionList != "" || len(f.Options) > 0):
+			return errors.New(fmt.Sprintf("Field '%s' of type %s cannot have options", f.Name, f.Type), 400)
+		}
+
+		if types, ok := columns[f.Name]; ok && !contains(types, f.Type) {
+			return errors.New(fmt.Sprintf("Field '%s' must be of type %s", f.Name, strings.Join(types, " or ")), 400)
+		}
+		if f.ShowIf != nil && (!seen[f.ShowIf.Field] || len(f.ShowIf.Values) == 0) {
+			return errors.New(fmt.Sprintf("Field '%s' can only depend on values of an earlier field", f.Name), 400)
+		}
+		seen[f.Name] = true
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,208 @@
+package forms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/model"
+	"github.com/Reskill-2022/volunteering/repository"
+	"github.com/Reskill-2022/volunteering/taxonomy"
+)
+
+const application = `{
+	"state": "Texas",
+	"organization": " Acme ",
+	"years_of_experience": "5-10",
+	"volunteer_areas": "Mentoring, Resume Review",
+	"volunteer_means": ["Remote"],
+	"convicted": false,
+	"representation": "Self",
+	"provided_name": "Jane",
+	"profile_url": "linkedin.com/in/Jane-Doe/",
+	"favourite_color": "blue"
+}`
+
+func decode(t *testing.T, body string) map[string]interface{} {
+	t.Helper()
+
+	var answers map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &answers); err != nil {
+		t.Fatalf("invalid test body: %v", err)
+	}
+	return answers
+}
+
+func TestCheckDefault(t *testing.T) {
+	answers, err := Check(*Default(), decode(t, application), taxonomy.Defaults())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var user model.User
+	if err := Apply(&user, DefaultVersion, answers); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user.Organization != "Acme" || user.VolunteerAreas.String() != "Mentoring,Resume Review" || user.State != "Texas" || user.FormVersion != 1 ||
+		user.ProfileURL != "https://www.linkedin.com/in/jane-doe" {
+		t.Errorf("unexpected user %+v", user)
+	}
+	if _, ok := user.Answers["favourite_color"]; ok {
+		t.Errorf("expected unknown answers to be dropped")
+	}
+
+	testCases := []struct {
+		field string
+		value interface{}
+		err   string
+	}{
+		{"state", nil, "Missing Field! State is required"},
+		{"provided_name", "  ", "Missing Field! Name is required"},
+		{"convicted", "no", "Invalid Answer! Convicted must be true or false"},
+		{"state", "TX", "Invalid State 'TX'. Choose one of the listed options"},
+		{"volunteer_means", []interface{}{"Remote", "Carrier Pigeon"}, "Invalid Volunteer Means 'Carrier Pigeon'. Choose one of the listed options"},
+		{"industries", "Finance,,Retail", "Invalid Industries. Separate industries with single commas"},
+	}
+	for _, tc := range testCases {
+		body := decode(t, application)
+		body[tc.field] = tc.value
+
+		_, err := Check(*Default(), body, taxonomy.Defaults())
+		if err == nil || err.(errors.Error).Message() != tc.err {
+			t.Errorf("%s=%v: expected %q, got %v", tc.field, tc.value, tc.err, err)
+		}
+	}
+}
+
+func TestCheckConditional(t *testing.T) {
+	form := model.Form{Version: 2, Fields: []model.Field{
+		{Name: "will_join_directory", Label: "Join the Directory", Type: model.FieldBoolean, Required: true},
+		{Name: "self_summary", Label: "Summary", Type: model.FieldTextarea, Required: true, ShowIf: &model.Condition{Field: "will_join_directory", Values: []string{"true"}}},
+		{Name: "hours", Label: "Hours per Week", Type: model.FieldNumber},
+	}}
+
+	testCases := []struct {
+		body string
+		want string
+		err  string
+	}{
+		{`{"will_join_directory": true, "self_summary": "Mentor", "hours": 4}`, "map[hours:4 self_summary:Mentor will_join_directory:true]", ""},
+		{`{"will_join_directory": false, "self_summary": "Mentor"}`, "map[will_join_directory:false]", ""},
+		{`{"will_join_directory": true}`, "", "Missing Field! Summary is required"},
+		{`{"will_join_directory": false, "hours": "4"}`, "", "Invalid Answer! Hours per Week must be a number"},
+	}
+	for _, tc := range testCases {
+		answers, err := Check(form, decode(t, tc.body), nil)
+		if tc.err != "" {
+			if err == nil || err.(errors.Error).Message() != tc.err {
+				t.Errorf("%s: expected %q, got %v", tc.body, tc.err, err)
+			}
+			continue
+		}
+		if err != nil || fmt.Sprint(answers) != tc.want {
+			t.Errorf("%s: expected %s, got %v, %v", tc.body, tc.want, answers, err)
+		}
+	}
+}
+
+func TestSubmission(t *testing.T) {
+	version, answers, err := Submission(decode(t, `{"form_version": 3, "state": "Ohio"}`))
+	if err != nil || version != 3 || len(answers) != 1 {
+		t.Errorf("unexpected submission %d %v %v", version, answers, err)
+	}
+	if version, _, err := Submission(decode(t, `{"state": "Ohio"}`)); err != nil || version != 0 {
+		t.Errorf("expected the current version, got %d %v", version, err)
+	}
+	if _, _, err := Submission(decode(t, `{"form_version": "3"}`)); err == nil {
+		t.Errorf("expected an error for a string version")
+	}
+}
+
+func TestCheckPartial(t *testing.T) {
+	answers, err := CheckPartial(*Default(), decode(t, `{"state": "Texas", "organization": null, "unknown": 1}`), taxonomy.Defaults())
+	if err != nil || fmt.Sprint(answers) != "map[organization:<nil> state:Texas]" {
+		t.Errorf("unexpected answers %v, %v", answers, err)
+	}
+
+	_, err = CheckPartial(*Default(), decode(t, `{"state": "TX"}`), taxonomy.Defaults())
+	if err == nil || err.(errors.Error).Message() != "Invalid State 'TX'. Choose one of the listed options" {
+		t.Errorf("expected an invalid state, got %v", err)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	if steps := fmt.Sprint(Steps(*Default())); steps != "[about volunteering declarations]" {
+		t.Errorf("unexpected steps %s", steps)
+	}
+
+	body := decode(t, application)
+	delete(body, "convicted")
+
+	steps := Progress(*Default(), body, taxonomy.Defaults())
+	if fmt.Sprint(steps) != "map[about:true declarations:false volunteering:true]" {
+		t.Errorf("unexpected progress %v", steps)
+	}
+
+	body["volunteer_means"] = []interface{}{"Carrier Pigeon"}
+	if steps := Progress(*Default(), body, taxonomy.Defaults()); steps["volunteering"] {
+		t.Errorf("expected an invalid answer to leave its step incomplete")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	text := func(name string) model.Field {
+		return model.Field{Name: name, Label: name, Type: model.FieldText}
+	}
+
+	testCases := []struct {
+		name   string
+		fields []model.Field
+		valid  bool
+	}{
+		{"default", Default().Fields, true},
+		{"empty", nil, false},
+		{"duplicate", []model.Field{text("a"), text("a")}, false},
+		{"unknown type", []model.Field{{Name: "a", Label: "A", Type: "date"}}, false},
+		{"select without options", []model.Field{{Name: "a", Label: "A", Type: model.FieldSelect}}, false},
+		{"unknown option list", []model.Field{{Name: "a", Label: "A", Type: model.FieldSelect, OptionList: "colors"}}, false},
+		{"text with options", []model.Field{{Name: "a", Label: "A", Type: model.FieldText, OptionList: taxonomy.States}}, false},
+		{"column type", []model.Field{{Name: "convicted", Label: "Convicted", Type: model.FieldText}}, false},
+		{"reserved", []model.Field{text("form_version")}, false},
+		{"later condition", []model.Field{{Name: "a", Label: "A", Type: model.FieldText, ShowIf: &model.Condition{Field: "b", Values: []string{"x"}}}, text("b")}, false},
+	}
+	for _, tc := range testCases {
+		if err := Validate(tc.fields); (err == nil) != tc.valid {
+			t.Errorf("%s: expected valid %t, got %v", tc.name, tc.valid, err)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	registry := NewRegistry(repository.NewMemoryUserRepository(zerolog.Nop()))
+
+	current, err := registry.Current(ctx)
+	if err != nil || current.Version != DefaultVersion {
+		t.Fatalf("expected the default form, got %+v, %v", current, err)
+	}
+
+	fields := []model.Field{{Name: "organization", Label: "Organization", Type: model.FieldText, Required: true}}
+	created, err := registry.Create(ctx, fields, "ops")
+	if err != nil || created.Version != 2 || created.CreatedBy != "ops" {
+		t.Fatalf("expected version 2, got %+v, %v", created, err)
+	}
+
+	if current, err = registry.Current(ctx); err != nil || current.Version != 2 {
+		t.Errorf("expected version 2 to be current, got %+v, %v", current, err)
+	}
+	if v1, err := registry.Get(ctx, 1); err != nil || len(v1.Fields) != len(Default().Fields) {
+		t.Errorf("expected the default form as version 1, got %+v, %v", v1, err)
+	}
+	if _, err := registry.Get(ctx, 3); errors.CodeFrom(err) != 400 {
+		t.Errorf("expected an unknown version error, got %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Reskill-2022/volunteering/config"
+	"github.com/Reskill-2022/volunteering/identity"
+)
+
+const (
+	DefaultAuthURL = "https://github.com"
+	DefaultAPIURL  = "https://api.github.com"
+)
+
+type (
+	AccessTokenResponse struct {
+		AccessToken      string `json:"access_token"`
+		TokenType        string `json:"token_type"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+
+	UserResponse struct {
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		AvatarURL string `json:"avatar_url"`
+		HTMLURL   string `json:"html_url"`
+		Location  string `json:"location"`
+	}
+
+	EmailResponse struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+
+	gh struct {
+		logger       zerolog.Logger
+		clientID     string
+		clientSecret string
+		authURL      string
+		apiURL       string
+		client       *http.Client
+	}
+)
+
+func New(logger zerolog.Logger, cfg config.OAuthClient) identity.Provider {
+	return NewWithOptions(logger, identity.Options{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+	})
+}
+
+func NewWithOptions(logger zerolog.Logger, opts identity.Options) identity.Provider {
+	g := &gh{
+		logger:       logger,
+		clientID:     opts.ClientID,
+		clientSecret: opts.ClientSecret,
+		authURL:      strings.TrimSuffix(opts.AuthURL, "/"),
+		apiURL:       strings.TrimSuffix(opts.APIURL, "/"),
+		client:       opts.HTTPClient,
+	}
+	if g.authURL == "" {
+		g.authURL = DefaultAuthURL
+	}
+	if g.apiURL == "" {
+		g.apiURL = DefaultAPIURL
+	}
+	if g.client == nil {
+		g.client = http.DefaultClient
+	}
+	return g
+}
+
+func (g *gh) GetProfile(authCode, redirectURI string) (*identity.Profile, error) {
+	token, err := g.exchangeCode(authCode, redirectURI)
+	if err != nil {
+		return nil, err
+	}
+
+	var user UserResponse
+	if err := g.get(token, "/user", &user); err != nil {
+		return nil, err
+	}
+
+	var emails []EmailResponse
+	if err := g.get(token, "/user/emails", &emails); err != nil {
+		return nil, err
+	}
+
+	email := primaryEmail(emails)
+	if email == "" {
+		return nil, fmt.Errorf("github account has no verified primary email")
+	}
+
+	name := user.Name
+	if name == "" {
+		name = user.Login
+	}
+
+	return &identity.Profile{
+		Email:      email,
+		Name:       name,
+		Photo:      user.AvatarURL,
+		ProfileURL: user.HTMLURL,
+		Location:   user.Location,
+	}, nil
+}
+
+func (g *gh) exchangeCode(authCode, redirectURI string) (string, error) {
+	data := url.Values{}
+	data.Set("code", authCode)
+	data.Set("client_id", g.clientID)
+	data.Set("client_secret", g.clientSecret)
+	data.Set("redirect_uri", redirectURI)
+
+	req, err := http.NewRequest(http.MethodPost, g.authURL+"/login/oauth/access_token", strings.NewReader(data.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to build request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		g.logger.Err(err).Msg("Failed to do request")
+		return "", fmt.Errorf("failed to get access token")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		g.logger.Error().Msgf("Token exchange failed with status %d: %s", resp.StatusCode, body)
+		return "", fmt.Errorf("failed to get access token, not 200 ok")
+	}
+
+	var payload AccessTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", fmt.Errorf("failed to unmarshal response body")
+	}
+	// GitHub reports a bad code with 200 OK and an error field.
+	if payload.Error != "" || payload.AccessToken == "" {
+		return "", fmt.Errorf("failed to get access token: %s", payload.ErrorDescription)
+	}
+	return payload.AccessToken, nil
+}
+
+func (g *gh) get(token, path string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, g.apiURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build request")
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to do request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get %s, not ok", path)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to unmarshal response body")
+	}
+	return nil
+}
+
+func primaryEmail(emails []EmailResponse) string {
+	for _, e := range emails {
+		if e.Primary && e.Verified {
+			return e.Email
+		}
+	}
+	return ""
+}
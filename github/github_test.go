@@ -0,0 +1,58 @@
+package github
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Reskill-2022/volunteering/identity"
+)
+
+func TestGetProfile(t *testing.T) {
+	emails := []EmailResponse{
+		{Email: "old@example.com", Primary: false, Verified: true},
+		{Email: "octo@example.com", Primary: true, Verified: true},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "good" {
+			json.NewEncoder(w).Encode(AccessTokenResponse{Error: "bad_verification_code", ErrorDescription: "The code passed is incorrect or expired."})
+			return
+		}
+		json.NewEncoder(w).Encode(AccessTokenResponse{AccessToken: "token", TokenType: "bearer"})
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(UserResponse{Login: "octocat", AvatarURL: "https://avatars.example.com/octocat", HTMLURL: "https://github.com/octocat"})
+	})
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(emails)
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	provider := NewWithOptions(zerolog.Nop(), identity.Options{AuthURL: srv.URL, APIURL: srv.URL, HTTPClient: srv.Client()})
+
+	got, err := provider.GetProfile("good", "http://localhost/callback")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := identity.Profile{Email: "octo@example.com", Name: "octocat", Photo: "https://avatars.example.com/octocat", ProfileURL: "https://github.com/octocat"}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("GetProfile() = %+v, want %+v", *got, want)
+	}
+
+	if _, err := provider.GetProfile("bad", "http://localhost/callback"); err == nil {
+		t.Errorf("expected error for rejected code")
+	}
+
+	emails[1].Verified = false
+	if _, err := provider.GetProfile("good", "http://localhost/callback"); err == nil {
+		t.Errorf("expected error when the primary email is unverified")
+	}
+}
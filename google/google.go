@@ -0,0 +1,159 @@
+package google
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Reskill-2022/volunteering/config"
+	"github.com/Reskill-2022/volunteering/identity"
+)
+
+const (
+	DefaultAuthURL = "https://oauth2.googleapis.com"
+	DefaultAPIURL  = "https://openidconnect.googleapis.com"
+)
+
+type (
+	TokenResponse struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+		IDToken     string `json:"id_token"`
+	}
+
+	UserInfoResponse struct {
+		Sub           string `json:"sub"`
+		Name          string `json:"name"`
+		GivenName     string `json:"given_name"`
+		FamilyName    string `json:"family_name"`
+		Picture       string `json:"picture"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+	}
+
+	ggl struct {
+		logger       zerolog.Logger
+		clientID     string
+		clientSecret string
+		authURL      string
+		apiURL       string
+		client       *http.Client
+	}
+)
+
+func New(logger zerolog.Logger, cfg config.OAuthClient) identity.Provider {
+	return NewWithOptions(logger, identity.Options{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+	})
+}
+
+func NewWithOptions(logger zerolog.Logger, opts identity.Options) identity.Provider {
+	g := &ggl{
+		logger:       logger,
+		clientID:     opts.ClientID,
+		clientSecret: opts.ClientSecret,
+		authURL:      strings.TrimSuffix(opts.AuthURL, "/"),
+		apiURL:       strings.TrimSuffix(opts.APIURL, "/"),
+		client:       opts.HTTPClient,
+	}
+	if g.authURL == "" {
+		g.authURL = DefaultAuthURL
+	}
+	if g.apiURL == "" {
+		g.apiURL = DefaultAPIURL
+	}
+	if g.client == nil {
+		g.client = http.DefaultClient
+	}
+	return g
+}
+
+func (g *ggl) GetProfile(authCode, redirectURI string) (*identity.Profile, error) {
+	token, err := g.exchangeCode(authCode, redirectURI)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := g.getUserInfo(token)
+	if err != nil {
+		return nil, err
+	}
+	if !info.EmailVerified || info.Email == "" {
+		return nil, fmt.Errorf("google email address is not verified")
+	}
+
+	name := info.Name
+	if name == "" {
+		name = strings.TrimSpace(info.GivenName + " " + info.FamilyName)
+	}
+
+	return &identity.Profile{
+		Email: info.Email,
+		Name:  name,
+		Photo: info.Picture,
+	}, nil
+}
+
+func (g *ggl) exchangeCode(authCode, redirectURI string) (string, error) {
+	data := url.Values{}
+	data.Set("grant_type", "authorization_code")
+	data.Set("code", authCode)
+	data.Set("client_id", g.clientID)
+	data.Set("client_secret", g.clientSecret)
+	data.Set("redirect_uri", redirectURI)
+
+	req, err := http.NewRequest(http.MethodPost, g.authURL+"/token", strings.NewReader(data.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to build request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		g.logger.Err(err).Msg("Failed to do request")
+		return "", fmt.Errorf("failed to get access token")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		g.logger.Error().Msgf("Token exchange failed with status %d: %s", resp.StatusCode, body)
+		return "", fmt.Errorf("failed to get access token, not 200 ok")
+	}
+
+	var payload TokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", fmt.Errorf("failed to unmarshal response body")
+	}
+	return payload.AccessToken, nil
+}
+
+func (g *ggl) getUserInfo(token string) (*UserInfoResponse, error) {
+	req, err := http.NewRequest(http.MethodGet, g.apiURL+"/v1/userinfo", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request")
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to do request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get userinfo, not ok")
+	}
+
+	var payload UserInfoResponse
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response body")
+	}
+	return &payload, nil
+}
@@ -0,0 +1,90 @@
+package google
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Reskill-2022/volunteering/identity"
+)
+
+func TestGetProfile(t *testing.T) {
+	info := UserInfoResponse{
+		Sub:           "1234",
+		GivenName:     "Jane",
+		FamilyName:    "Doe",
+		Picture:       "https://photos.example.com/jane",
+		Email:         "jane@example.com",
+		EmailVerified: true,
+	}
+	userInfoStatus := http.StatusOK
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != "authorization_code" || r.FormValue("client_id") != "client" || r.FormValue("redirect_uri") != "http://localhost/callback" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.FormValue("code") != "good" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "invalid_grant"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(TokenResponse{AccessToken: "token", ExpiresIn: 3600})
+	})
+	mux.HandleFunc("/v1/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if userInfoStatus != http.StatusOK {
+			w.WriteHeader(userInfoStatus)
+			return
+		}
+		json.NewEncoder(w).Encode(info)
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	provider := NewWithOptions(zerolog.Nop(), identity.Options{ClientID: "client", ClientSecret: "secret", AuthURL: srv.URL, APIURL: srv.URL, HTTPClient: srv.Client()})
+
+	got, err := provider.GetProfile("good", "http://localhost/callback")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the name falls back to the given and family names
+	want := identity.Profile{Email: "jane@example.com", Name: "Jane Doe", Photo: "https://photos.example.com/jane"}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("GetProfile() = %+v, want %+v", *got, want)
+	}
+
+	if _, err := provider.GetProfile("bad", "http://localhost/callback"); err == nil {
+		t.Errorf("expected error for rejected code")
+	}
+
+	userInfoStatus = http.StatusInternalServerError
+	if _, err := provider.GetProfile("good", "http://localhost/callback"); err == nil {
+		t.Errorf("expected error when the userinfo call fails")
+	}
+	userInfoStatus = http.StatusOK
+
+	info.EmailVerified = false
+	if _, err := provider.GetProfile("good", "http://localhost/callback"); err == nil {
+		t.Errorf("expected error when the email is unverified")
+	}
+}
+
+func TestGetProfileUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	provider := NewWithOptions(zerolog.Nop(), identity.Options{AuthURL: srv.URL, APIURL: srv.URL})
+	if _, err := provider.GetProfile("good", "http://localhost/callback"); err == nil {
+		t.Errorf("expected error when the token endpoint is unreachable")
+	}
+}
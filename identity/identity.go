@@ -0,0 +1,75 @@
+// Package identity defines the sign-in providers a volunteer can use to prove
+// who they are when creating an account.
+package identity
+
+import (
+	"net/http"
+	"sort"
+)
+
+const (
+	LinkedIn = "linkedin"
+	Google   = "google"
+	GitHub   = "github"
+)
+
+var displayNames = map[string]string{
+	LinkedIn: "LinkedIn",
+	Google:   "Google",
+	GitHub:   "GitHub",
+}
+
+type (
+	// Provider exchanges an OAuth authorization code for a verified profile.
+	Provider interface {
+		GetProfile(authCode, redirectURI string) (*Profile, error)
+	}
+
+	// Profile is the verified identity returned by a Provider.
+	Profile struct {
+		Email         string
+		Name          string
+		Photo         string
+		ProfileURL    string
+		Location      string
+		Phone         string
+		HasExperience bool
+		// Positions are the titles of the profile's positions, most
+		// recent first.
+		Positions []string
+	}
+
+	// Options configures an OAuth Provider. Zero-valued URLs and client fall
+	// back to the provider's public endpoints and http.DefaultClient.
+	Options struct {
+		ClientID     string
+		ClientSecret string
+		AuthURL      string
+		APIURL       string
+		// Issuer is the expected id_token issuer of OpenID Connect
+		// providers, derived from AuthURL when empty.
+		Issuer     string
+		HTTPClient *http.Client
+	}
+
+	// Providers maps a provider name, as sent by the frontend, to its Provider.
+	Providers map[string]Provider
+)
+
+// Names returns the sorted names of the configured providers.
+func (p Providers) Names() []string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DisplayName returns the human-readable name of a provider.
+func DisplayName(provider string) string {
+	if name, ok := displayNames[provider]; ok {
+		return name
+	}
+	return provider
+}
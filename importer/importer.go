@@ -0,0 +1,322 @@
+// Package importer merges volunteer lists from partner organizations into the
+// user repository. Rows answer the application form; they are checked against
+// it like applications submitted through the API, deduplicated by email and
+// reported on one by one.
+package importer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/forms"
+	"github.com/Reskill-2022/volunteering/model"
+	"github.com/Reskill-2022/volunteering/repository"
+	"github.com/Reskill-2022/volunteering/review"
+	"github.com/Reskill-2022/volunteering/taxonomy"
+)
+
+const (
+	CSV   = "csv"
+	JSONL = "jsonl"
+)
+
+// Provider is recorded on imported users in place of an identity provider.
+const Provider = "import"
+
+// versionKey is the column naming the form version a row answers, as in
+// API submissions.
+const versionKey = "form_version"
+
+// DefaultBatchSize is the number of users written concurrently.
+const DefaultBatchSize = 25
+
+// Row statuses.
+const (
+	StatusCreated     = "created"
+	StatusWouldCreate = "would-create"
+	StatusDuplicate   = "duplicate"
+	StatusInvalid     = "invalid"
+	StatusFailed      = "failed"
+)
+
+type (
+	// Record is one volunteer read from an import file.
+	Record struct {
+		Email string
+		Name  string
+		Phone string
+		// Answers are keyed by form field name, optionally with the
+		// form_version they answer.
+		Answers map[string]interface{}
+
+		// text marks answers read as CSV cells, which are converted to the
+		// types of their fields.
+		text bool
+		// err is set when the record could not be read.
+		err error
+	}
+
+	Options struct {
+		Format string
+		// DryRun validates and deduplicates without writing.
+		DryRun    bool
+		BatchSize int
+		// Form is the form rows answer, forms.Default() when nil.
+		Form *model.Form
+		// Taxonomy holds the option lists of the form, taxonomy.Defaults()
+		// when nil.
+		Taxonomy taxonomy.Taxonomy
+	}
+
+	// Row reports what happened to one record. Line is the line of the
+	// record in the file.
+	Row struct {
+		Line    int    `json:"line"`
+		Email   string `json:"email"`
+		Status  string `json:"status"`
+		Message string `json:"message,omitempty"`
+	}
+
+	Report struct {
+		Rows   []Row          `json:"rows"`
+		Counts map[string]int `json:"counts"`
+	}
+
+	// Store is what an import needs from the user repository.
+	Store interface {
+		repository.UserGetter
+		repository.UserCreator
+	}
+
+	// pending is a valid record waiting to be written.
+	pending struct {
+		row  int
+		user model.User
+	}
+)
+
+// Import reads every record from r, validates it and creates the users that
+// do not exist yet in batches of opts.BatchSize.
+func Import(ctx context.Context, r io.Reader, store Store, opts Options) (*Report, error) {
+	var (
+		records []Record
+		lines   []int
+		err     error
+	)
+	switch opts.Format {
+	case CSV:
+		records, lines, err = ReadCSV(r)
+	case JSONL:
+		records, lines, err = ReadJSONL(r)
+	default:
+		return nil, fmt.Errorf("unsupported import format '%s', use %s or %s", opts.Format, CSV, JSONL)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	form, lists := opts.Form, opts.Taxonomy
+	if form == nil {
+		form = forms.Default()
+	}
+	if lists == nil {
+		lists = taxonomy.Defaults()
+	}
+
+	report := &Report{Rows: make([]Row, len(records)), Counts: map[string]int{}}
+	seen := make(map[string]int)
+	now := time.Now().UTC()
+
+	var batch []pending
+	flush := func() {
+		if !opts.DryRun {
+			write(ctx, store, batch, report)
+		}
+		batch = batch[:0]
+	}
+
+	for i, rec := range records {
+		row := &report.Rows[i]
+		row.Line = lines[i]
+		row.Email = strings.TrimSpace(rec.Email)
+
+		user, err := rec.user(*form, lists, now)
+		if err != nil {
+			row.Status, row.Message = StatusInvalid, message(err)
+			continue
+		}
+
+		key := strings.ToLower(user.Email)
+		if first, ok := seen[key]; ok {
+			row.Status, row.Message = StatusDuplicate, fmt.Sprintf("same email as line %d", first)
+			continue
+		}
+		seen[key] = row.Line
+
+		_, err = store.GetUser(ctx, user.Email)
+		if err == nil {
+			row.Status, row.Message = StatusDuplicate, "already registered"
+			continue
+		}
+		if errors.CodeFrom(err) != 404 {
+			row.Status, row.Message = StatusFailed, message(err)
+			continue
+		}
+
+		row.Status = StatusWouldCreate
+		batch = append(batch, pending{row: i, user: user})
+		if len(batch) == batchSize {
+			flush()
+		}
+	}
+	flush()
+
+	for _, row := range report.Rows {
+		report.Counts[row.Status]++
+	}
+	return report, nil
+}
+
+// write creates a batch of users concurrently.
+func write(ctx context.Context, store Store, batch []pending, report *Report) {
+	var wg sync.WaitGroup
+	for _, p := range batch {
+		wg.Add(1)
+		go func(p pending) {
+			defer wg.Done()
+
+			row := &report.Rows[p.row]
+			_, created, err := store.CreateUser(ctx, p.user)
+			if err != nil {
+				row.Status, row.Message = StatusFailed, message(err)
+				return
+			}
+			if !created {
+				// registered since it was checked
+				row.Status, row.Message = StatusDuplicate, "already registered"
+				return
+			}
+			row.Status = StatusCreated
+		}(p)
+	}
+	wg.Wait()
+}
+
+// user checks the answers of rec against form like an application submitted
+// through the API and returns the enrolled user it describes.
+func (rec Record) user(form model.Form, lists taxonomy.Taxonomy, now time.Time) (model.User, error) {
+	if rec.err != nil {
+		return model.User{}, rec.err
+	}
+
+	email := strings.TrimSpace(rec.Email)
+	if email == "" {
+		return model.User{}, errors.New("Missing Field! Email is required", 400)
+	}
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return model.User{}, errors.New(fmt.Sprintf("Invalid Email '%s'", rec.Email), 400)
+	}
+
+	answers := make(map[string]interface{}, len(rec.Answers))
+	for name, value := range rec.Answers {
+		answers[name] = value
+	}
+	if rec.text {
+		if err := convert(form, answers); err != nil {
+			return model.User{}, err
+		}
+	}
+	version, answers, err := forms.Submission(answers)
+	if err != nil {
+		return model.User{}, err
+	}
+	if version != 0 && version != form.Version {
+		return model.User{}, errors.New(fmt.Sprintf("Rows must answer Form Version %d", form.Version), 400)
+	}
+	answers, err = forms.Check(form, answers, lists)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	user := model.User{
+		Email:     email,
+		Phone:     strings.TrimSpace(rec.Phone),
+		Provider:  Provider,
+		Enrolled:  true,
+		CreatedAt: now,
+	}
+	if err := forms.Apply(&user, form.Version, answers); err != nil {
+		return model.User{}, err
+	}
+
+	name := strings.TrimSpace(rec.Name)
+	if name == "" {
+		name = user.ProvidedName
+	}
+	// split like names verified at sign-in
+	names := strings.Fields(name)
+	if len(names) == 0 {
+		return model.User{}, errors.New("Missing Field! Name is required", 400)
+	}
+	user.Name, user.FirstName = name, names[0]
+	if len(names) > 1 {
+		user.LastName = names[len(names)-1]
+	}
+
+	review.Submit(&user, now)
+	return user, nil
+}
+
+// convert turns the CSV cells in answers into the types of their fields:
+// yes/no booleans, numbers and lists separated by commas or semicolons.
+func convert(form model.Form, answers map[string]interface{}) error {
+	if raw, ok := answers[versionKey].(string); ok {
+		version, err := strconv.Atoi(raw)
+		if err != nil {
+			return errors.New("Invalid Form Version", 400)
+		}
+		answers[versionKey] = float64(version)
+	}
+
+	for _, f := range form.Fields {
+		cell, ok := answers[f.Name].(string)
+		if !ok {
+			continue
+		}
+		switch f.Type {
+		case model.FieldBoolean:
+			b, ok := parseYesNo(cell)
+			if !ok {
+				return errors.New(fmt.Sprintf("%s must be yes or no, got '%s'", f.Label, cell), 400)
+			}
+			answers[f.Name] = b
+		case model.FieldNumber:
+			n, err := strconv.ParseFloat(cell, 64)
+			if err != nil {
+				return errors.New(fmt.Sprintf("%s must be a number, got '%s'", f.Label, cell), 400)
+			}
+			answers[f.Name] = n
+		case model.FieldMultiselect:
+			answers[f.Name] = []string(splitList(cell))
+		}
+	}
+	return nil
+}
+
+func message(err error) string {
+	if e, ok := err.(errors.Error); ok {
+		return e.Message()
+	}
+	return err.Error()
+}
@@ -0,0 +1,182 @@
+package importer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Reskill-2022/volunteering/forms"
+	"github.com/Reskill-2022/volunteering/model"
+	"github.com/Reskill-2022/volunteering/repository"
+	"github.com/Reskill-2022/volunteering/taxonomy"
+)
+
+const testCSV = `Email,Name,State,Organization,Years of Experience,Volunteer Areas,Volunteer Means,Convicted,Representation,Provided Name
+jane@example.com,Jane Doe,Texas,Acme,5-10,Mentoring; Career Coaching,Remote,no,Self,Jane
+existing@example.com,Existing User,Ohio,Acme,1-5,Mentoring,Remote,no,Self,Existing
+JANE@example.com,Jane Again,Texas,Acme,5-10,Mentoring,Remote,no,Self,Jane
+not-an-email,Bad Email,Texas,Acme,5-10,Mentoring,Remote,no,Self,Bad
+john@example.com,John Roe,,Acme,5-10,Mentoring,Remote,no,Self,John
+mary@example.com,Mary Major,Utah,Acme,1-5,Research,"Remote,In person",maybe,Self,Mary
+sam@example.com,Sam Poe,Utah,Acme,1-5,Resume Review,In Person,yes,Organization,Sam
+`
+
+func testStore(t *testing.T) *repository.MemoryUserRepository {
+	t.Helper()
+
+	repo := repository.NewMemoryUserRepository(zerolog.Nop())
+	if _, _, err := repo.CreateUser(context.Background(), model.User{Email: "existing@example.com", Name: "Existing User"}); err != nil {
+		t.Fatalf("failed to seed users: %v", err)
+	}
+	return repo
+}
+
+func TestImportCSV(t *testing.T) {
+	ctx := context.Background()
+
+	testCases := []struct {
+		dryRun bool
+		status string
+	}{
+		{true, StatusWouldCreate},
+		{false, StatusCreated},
+	}
+
+	for _, tc := range testCases {
+		store := testStore(t)
+
+		report, err := Import(ctx, strings.NewReader(testCSV), store, Options{Format: CSV, DryRun: tc.dryRun, BatchSize: 1})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		want := []struct {
+			line   int
+			status string
+		}{
+			{2, tc.status},
+			{3, StatusDuplicate},
+			{4, StatusDuplicate},
+			{5, StatusInvalid},
+			{6, StatusInvalid},
+			{7, StatusInvalid},
+			{8, tc.status},
+		}
+		if len(report.Rows) != len(want) {
+			t.Fatalf("expected %d rows, got %+v", len(want), report.Rows)
+		}
+		for i, w := range want {
+			if row := report.Rows[i]; row.Line != w.line || row.Status != w.status {
+				t.Errorf("dry run %t: row %d = %+v, want line %d status %s", tc.dryRun, i, row, w.line, w.status)
+			}
+		}
+		if report.Counts[tc.status] != 2 {
+			t.Errorf("dry run %t: unexpected counts %v", tc.dryRun, report.Counts)
+		}
+
+		user, err := store.GetUser(ctx, "jane@example.com")
+		if tc.dryRun {
+			if err == nil {
+				t.Errorf("dry run created a user")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("expected imported user: %v", err)
+		}
+		if !user.Enrolled || user.VolunteerAreas.String() != "Mentoring,Career Coaching" || user.LastName != "Doe" || user.Provider != Provider {
+			t.Errorf("unexpected imported user %+v", user)
+		}
+		// stored like applications submitted through the API
+		if user.FormVersion != forms.DefaultVersion || user.Answers["state"] != "Texas" || user.Answers["convicted"] != false {
+			t.Errorf("unexpected answers %v of form version %d", user.Answers, user.FormVersion)
+		}
+	}
+}
+
+func TestImportJSONL(t *testing.T) {
+	input := `{"email": "jane@example.com", "name": "Jane Doe", "state": "Texas", "organization": "Acme", "years_of_experience": "5-10", "volunteer_areas": ["Mentoring"], "volunteer_means": ["Remote"], "convicted": false, "representation": "Self", "provided_name": "Jane"}
+
+{"email": "john@example.com", "convicted": "no"}
+{"email": "joan@example.com", "state": "Texas", "organization": "Acme", "years_of_experience": "5-10", "volunteer_areas": ["Mentoring"], "volunteer_means": ["Remote"], "convicted": false, "representation": "Self", "provided_name": "   "}
+`
+	report, err := Import(context.Background(), strings.NewReader(input), testStore(t), Options{Format: JSONL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(report.Rows) != 3 {
+		t.Fatalf("expected 3 rows, got %+v", report.Rows)
+	}
+	if row := report.Rows[0]; row.Status != StatusCreated {
+		t.Errorf("unexpected first row %+v", row)
+	}
+	if row := report.Rows[1]; row.Line != 3 || row.Status != StatusInvalid {
+		t.Errorf("unexpected second row %+v", row)
+	}
+	// a blank name is reported, not split
+	if row := report.Rows[2]; row.Line != 4 || row.Status != StatusInvalid {
+		t.Errorf("unexpected third row %+v", row)
+	}
+}
+
+func TestImportTaxonomy(t *testing.T) {
+	lists := taxonomy.Defaults()
+	lists[taxonomy.Areas] = []model.Option{{Value: "Mentoring", Label: "Mentoring"}}
+
+	report, err := Import(context.Background(), strings.NewReader(testCSV), testStore(t), Options{
+		Format:   CSV,
+		DryRun:   true,
+		Taxonomy: lists,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if row := report.Rows[0]; row.Status != StatusInvalid || row.Message != "Invalid Volunteer Areas 'Career Coaching'. Choose one of the listed options" {
+		t.Errorf("unexpected first row %+v", row)
+	}
+	if row := report.Rows[6]; row.Status != StatusInvalid || !strings.Contains(row.Message, "Resume Review") {
+		t.Errorf("unexpected last row %+v", row)
+	}
+}
+
+func TestImportIndustries(t *testing.T) {
+	input := `Email,Name,State,Organization,Years of Experience,Volunteer Areas,Volunteer Means,Convicted,Representation,Provided Name,Industries
+jane@example.com,Jane Doe,Texas,Acme,5-10,Mentoring,Remote,no,Self,Jane,"Finance, Health Care"
+john@example.com,John Roe,Texas,Acme,5-10,Mentoring,Remote,no,Self,John,"Finance,,Health Care"
+`
+	report, err := Import(context.Background(), strings.NewReader(input), testStore(t), Options{Format: CSV, DryRun: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if row := report.Rows[0]; row.Status != StatusWouldCreate {
+		t.Errorf("unexpected first row %+v", row)
+	}
+	// rejected like the API rejects it
+	if row := report.Rows[1]; row.Status != StatusInvalid || !strings.Contains(row.Message, "single commas") {
+		t.Errorf("unexpected second row %+v", row)
+	}
+}
+
+func TestImportEscapedCells(t *testing.T) {
+	input := `Email,Name,Phone,State,Organization,Years of Experience,Volunteer Areas,Volunteer Means,Convicted,Representation,Provided Name
+jane@example.com,Jane Doe,'+1 512 555 0100,Texas,'-Acme,5-10,Mentoring,Remote,no,Self,Jane
+`
+	store := testStore(t)
+	if _, err := Import(context.Background(), strings.NewReader(input), store, Options{Format: CSV}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// exported cells read back as they were
+	user, err := store.GetUser(context.Background(), "jane@example.com")
+	if err != nil {
+		t.Fatalf("expected imported user: %v", err)
+	}
+	if user.Phone != "+1 512 555 0100" || user.Organization != "-Acme" {
+		t.Errorf("unexpected phone %q and organization %q", user.Phone, user.Organization)
+	}
+}
@@ -0,0 +1,169 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/model"
+)
+
+// ReadCSV reads records from a CSV file whose first row names the columns.
+// Column names are matched case-insensitively with spaces treated as
+// underscores, so both "years_of_experience" and the export's "Years of
+// Experience" work. Every column but email, name and phone is an answer to
+// the form field it names: multiple choices are separated by commas or
+// semicolons and booleans such as Convicted are yes/no or true/false. Empty
+// cells are unanswered, and the quote exports put in front of cells starting
+// with =, +, - or @ is removed. It returns the records and their line numbers.
+func ReadCSV(r io.Reader) ([]Record, []int, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+	columns := make([]string, len(header))
+	seen := make(map[string]bool)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		columns[i] = strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+		seen[columns[i]] = true
+	}
+	if !seen["email"] {
+		return nil, nil, fmt.Errorf("csv has no email column")
+	}
+
+	var (
+		records []Record
+		lines   []int
+	)
+	for {
+		fields, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			parseErr, ok := err.(*csv.ParseError)
+			if !ok {
+				return nil, nil, err
+			}
+			records = append(records, Record{err: errors.From(err, "Malformed CSV row", 400)})
+			lines = append(lines, parseErr.StartLine)
+			continue
+		}
+		line, _ := cr.FieldPos(0)
+
+		rec := Record{Answers: make(map[string]interface{}), text: true}
+		for i, cell := range fields {
+			if i >= len(columns) {
+				break
+			}
+			cell = unescapeFormula(strings.TrimSpace(cell))
+			switch columns[i] {
+			case "email":
+				rec.Email = cell
+			case "name":
+				rec.Name = cell
+			case "phone":
+				rec.Phone = cell
+			default:
+				if cell != "" {
+					rec.Answers[columns[i]] = cell
+				}
+			}
+		}
+
+		records = append(records, rec)
+		lines = append(lines, line)
+	}
+
+	return records, lines, nil
+}
+
+// ReadJSONL reads one JSON object per line: the email, name and phone of the
+// volunteer and their answers, keyed by field name as in the API. Blank lines
+// are skipped. It returns the records and their line numbers.
+func ReadJSONL(r io.Reader) ([]Record, []int, error) {
+	var (
+		records []Record
+		lines   []int
+	)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		records = append(records, readJSON(raw))
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return records, lines, nil
+}
+
+func readJSON(raw []byte) Record {
+	var answers map[string]interface{}
+	if err := json.Unmarshal(raw, &answers); err != nil {
+		return Record{err: errors.From(err, "Malformed JSON line", 400)}
+	}
+
+	rec := Record{Answers: answers}
+	for key, field := range map[string]*string{"email": &rec.Email, "name": &rec.Name, "phone": &rec.Phone} {
+		value, ok := answers[key]
+		delete(answers, key)
+		if !ok || value == nil {
+			continue
+		}
+		s, ok := value.(string)
+		if !ok {
+			return Record{err: errors.New(fmt.Sprintf("Invalid JSON line. %s must be text", key), 400)}
+		}
+		*field = s
+	}
+	return rec
+}
+
+// splitList splits a cell of comma or semicolon separated values, returning
+// nil for an empty cell.
+func splitList(cell string) model.StringList {
+	var values model.StringList
+	for _, v := range strings.FieldsFunc(cell, func(r rune) bool { return r == ',' || r == ';' }) {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// unescapeFormula removes the quote exports put in front of cells starting
+// with a formula character.
+func unescapeFormula(cell string) string {
+	if len(cell) > 1 && cell[0] == '\'' && strings.ContainsRune("=+-@", rune(cell[1])) {
+		return cell[1:]
+	}
+	return cell
+}
+
+func parseYesNo(value string) (bool, bool) {
+	switch strings.ToLower(value) {
+	case "yes", "y", "true":
+		return true, true
+	case "no", "n", "false":
+		return false, true
+	}
+	return false, false
+}
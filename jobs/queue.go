@@ -0,0 +1,399 @@
+// Package jobs runs side effects, such as mirror writes and webhook
+// deliveries, in the background. Jobs are kept in a bolt file until they
+// succeed, so they survive restarts, and are retried with exponential backoff.
+// Jobs that keep failing are moved to a dead-letter list, from which an admin
+// can retry them. A job may run more than once, so handlers must be
+// idempotent.
+package jobs
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/Reskill-2022/volunteering/errors"
+)
+
+// Job states.
+const (
+	StatePending = "pending"
+	StateDead    = "dead"
+)
+
+var (
+	pendingBucket = []byte(StatePending)
+	deadBucket    = []byte(StateDead)
+)
+
+// pollInterval bounds how long a due job waits when no job is enqueued.
+const pollInterval = time.Second
+
+// maxBackoff bounds the delay between attempts.
+const maxBackoff = time.Hour
+
+// Job is one unit of background work.
+type Job struct {
+	ID          uint64          `json:"id"`
+	Kind        string          `json:"kind"`
+	Payload     json.RawMessage `json:"payload"`
+	State       string          `json:"state"`
+	Attempts    int             `json:"attempts"`
+	LastError   string          `json:"last_error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	NextAttempt time.Time       `json:"next_attempt"`
+}
+
+// Handler runs a job of one kind with its payload. A returned error schedules
+// a retry.
+type Handler func(ctx context.Context, payload json.RawMessage) error
+
+// Options tune a Queue. Zero values take the defaults.
+type Options struct {
+	// Workers is the number of jobs run at once, 4 by default.
+	Workers int
+	// RetryInterval is the delay before the first retry, doubling after
+	// each, 10 seconds by default.
+	RetryInterval time.Duration
+	// MaxAttempts is the number of attempts before a job is dead-lettered,
+	// 8 by default.
+	MaxAttempts int
+	// Timeout bounds each attempt, 30 seconds by default.
+	Timeout time.Duration
+}
+
+func (o Options) withDefaults() Options {
+	if o.Workers <= 0 {
+		o.Workers = 4
+	}
+	if o.RetryInterval <= 0 {
+		o.RetryInterval = 10 * time.Second
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 8
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 30 * time.Second
+	}
+	return o
+}
+
+// Queue is a bolt-backed job queue.
+type Queue struct {
+	logger zerolog.Logger
+	db     *bolt.DB
+	opts   Options
+
+	mu       sync.Mutex
+	handlers map[string]Handler
+	running  map[uint64]bool
+	wake     chan struct{}
+}
+
+func Open(logger zerolog.Logger, path string, opts Options) (*Queue, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open job queue: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{pendingBucket, deadBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialise job queue: %w", err)
+	}
+
+	return &Queue{
+		logger:   logger,
+		db:       db,
+		opts:     opts.withDefaults(),
+		handlers: make(map[string]Handler),
+		running:  make(map[uint64]bool),
+		wake:     make(chan struct{}, 1),
+	}, nil
+}
+
+func (q *Queue) Close() error {
+	return q.db.Close()
+}
+
+// Register sets the handler of kind. Jobs of kinds without a handler wait
+// until one is registered.
+func (q *Queue) Register(kind string, handler Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[kind] = handler
+}
+
+// Enqueue durably records a job of kind with payload encoded as JSON, to run
+// as soon as a worker is free.
+func (q *Queue) Enqueue(kind string, payload interface{}) (Job, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return Job{}, fmt.Errorf("failed to encode %s job: %w", kind, err)
+	}
+
+	now := time.Now().UTC()
+	job := Job{Kind: kind, Payload: raw, State: StatePending, CreatedAt: now, NextAttempt: now}
+	err = q.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(pendingBucket)
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		job.ID = id
+		return put(bucket, job)
+	})
+	if err != nil {
+		return Job{}, fmt.Errorf("failed to enqueue %s job: %w", kind, err)
+	}
+
+	q.signal()
+	return job, nil
+}
+
+func (q *Queue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run starts the workers and hands them due jobs until ctx is done. Jobs
+// interrupted by shutdown run again on the next start.
+func (q *Queue) Run(ctx context.Context) {
+	work := make(chan Job)
+	var wg sync.WaitGroup
+	for i := 0; i < q.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range work {
+				q.run(ctx, job)
+			}
+		}()
+	}
+	defer wg.Wait()
+	defer close(work)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		due, err := q.claim(time.Now().UTC())
+		if err != nil {
+			q.logger.Err(err).Msg("Jobs: failed to read due jobs")
+		}
+		for _, job := range due {
+			select {
+			case work <- job:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-q.wake:
+		}
+	}
+}
+
+// claim returns the due jobs that have a handler and are not running, marking
+// them as running.
+func (q *Queue) claim(now time.Time) ([]Job, error) {
+	pending, err := q.list(pendingBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var due []Job
+	for _, job := range pending {
+		if job.NextAttempt.After(now) || q.running[job.ID] || q.handlers[job.Kind] == nil {
+			continue
+		}
+		q.running[job.ID] = true
+		due = append(due, job)
+	}
+	return due, nil
+}
+
+func (q *Queue) run(ctx context.Context, job Job) {
+	defer func() {
+		q.mu.Lock()
+		delete(q.running, job.ID)
+		q.mu.Unlock()
+		// a retry may already be due
+		q.signal()
+	}()
+
+	q.mu.Lock()
+	handler := q.handlers[job.Kind]
+	q.mu.Unlock()
+
+	attemptCtx, cancel := context.WithTimeout(ctx, q.opts.Timeout)
+	err := handler(attemptCtx, job.Payload)
+	cancel()
+
+	if err == nil {
+		if err := q.remove(pendingBucket, job.ID); err != nil {
+			q.logger.Err(err).Msgf("Jobs: failed to complete %s job %d", job.Kind, job.ID)
+		}
+		return
+	}
+	if ctx.Err() != nil {
+		// shutting down; the job runs again on the next start
+		return
+	}
+
+	job.Attempts++
+	job.LastError = err.Error()
+	if job.Attempts >= q.opts.MaxAttempts {
+		q.logger.Error().Err(err).Msgf("Jobs: %s job %d failed %d times, dead-lettering", job.Kind, job.ID, job.Attempts)
+		job.State = StateDead
+		err = q.move(job, pendingBucket, deadBucket)
+	} else {
+		delay := Backoff(q.opts.RetryInterval, job.Attempts-1)
+		job.NextAttempt = time.Now().UTC().Add(delay)
+		q.logger.Warn().Err(err).Msgf("Jobs: %s job %d failed, retrying at %s", job.Kind, job.ID, job.NextAttempt.Format(time.RFC3339))
+		err = q.db.Update(func(tx *bolt.Tx) error { return put(tx.Bucket(pendingBucket), job) })
+		time.AfterFunc(delay, q.signal)
+	}
+	if err != nil {
+		q.logger.Err(err).Msgf("Jobs: failed to record failure of %s job %d", job.Kind, job.ID)
+	}
+}
+
+// List returns the jobs in state, oldest first.
+func (q *Queue) List(state string) ([]Job, error) {
+	switch state {
+	case StatePending:
+		return q.list(pendingBucket)
+	case StateDead:
+		return q.list(deadBucket)
+	default:
+		return nil, errors.New(fmt.Sprintf("Invalid State '%s'. Use %s or %s", state, StatePending, StateDead), 400)
+	}
+}
+
+// Retry moves the dead job id back to the queue, to run now with a fresh
+// set of attempts.
+func (q *Queue) Retry(id uint64) (Job, error) {
+	var job Job
+	err := q.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(deadBucket).Get(key(id))
+		if raw == nil {
+			return errors.New("Dead Job Not Found", 404)
+		}
+		return json.Unmarshal(raw, &job)
+	})
+	if err != nil {
+		if errors.CodeFrom(err) == 404 {
+			return Job{}, err
+		}
+		return Job{}, errors.From(err, "failed to read job", 500)
+	}
+
+	job.State = StatePending
+	job.Attempts = 0
+	job.NextAttempt = time.Now().UTC()
+	if err := q.move(job, deadBucket, pendingBucket); err != nil {
+		return Job{}, errors.From(err, "failed to retry job", 500)
+	}
+
+	q.signal()
+	return job, nil
+}
+
+// Stats counts the jobs in each state.
+func (q *Queue) Stats() (map[string]int, error) {
+	stats := make(map[string]int)
+	err := q.db.View(func(tx *bolt.Tx) error {
+		stats[StatePending] = tx.Bucket(pendingBucket).Stats().KeyN
+		stats[StateDead] = tx.Bucket(deadBucket).Stats().KeyN
+		return nil
+	})
+	if err != nil {
+		return nil, errors.From(err, "failed to count jobs", 500)
+	}
+	return stats, nil
+}
+
+func (q *Queue) list(bucket []byte) ([]Job, error) {
+	jobs := []Job{}
+	err := q.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).ForEach(func(_, v []byte) error {
+			var job Job
+			if err := json.Unmarshal(v, &job); err != nil {
+				return err
+			}
+			jobs = append(jobs, job)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errors.From(err, "failed to read jobs", 500)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	return jobs, nil
+}
+
+func (q *Queue) remove(bucket []byte, id uint64) error {
+	return q.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Delete(key(id))
+	})
+}
+
+func (q *Queue) move(job Job, from, to []byte) error {
+	return q.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(from).Delete(key(job.ID)); err != nil {
+			return err
+		}
+		return put(tx.Bucket(to), job)
+	})
+}
+
+func put(bucket *bolt.Bucket, job Job) error {
+	raw, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(key(job.ID), raw)
+}
+
+// key encodes id big-endian so that bolt iterates jobs in enqueue order.
+func key(id uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, id)
+	return b
+}
+
+// Backoff returns the delay before retry number attempts+1, doubling from
+// base up to a maximum of one hour.
+func Backoff(base time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 0; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
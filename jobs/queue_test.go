@@ -0,0 +1,155 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func openQueue(t *testing.T, path string, opts Options) *Queue {
+	t.Helper()
+
+	q, err := Open(zerolog.Nop(), path, opts)
+	if err != nil {
+		t.Fatalf("failed to open queue: %v", err)
+	}
+	return q
+}
+
+// waitFor polls until cond holds or a second passes.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestQueue(t *testing.T) {
+	q := openQueue(t, filepath.Join(t.TempDir(), "jobs.db"), Options{Workers: 2, RetryInterval: time.Millisecond, MaxAttempts: 3})
+	defer q.Close()
+
+	var (
+		mu       sync.Mutex
+		attempts = make(map[string]int)
+	)
+	q.Register("echo", func(ctx context.Context, payload json.RawMessage) error {
+		var name string
+		if err := json.Unmarshal(payload, &name); err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[name]++
+		switch {
+		case name == "broken":
+			return fmt.Errorf("always fails")
+		case name == "flaky" && attempts[name] < 2:
+			return fmt.Errorf("fails once")
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		q.Run(ctx)
+		close(done)
+	}()
+
+	for _, name := range []string{"ok", "flaky", "broken"} {
+		if _, err := q.Enqueue("echo", name); err != nil {
+			t.Fatalf("failed to enqueue: %v", err)
+		}
+	}
+
+	waitFor(t, "the queue to drain", func() bool {
+		stats, _ := q.Stats()
+		return stats[StatePending] == 0 && stats[StateDead] == 1
+	})
+
+	mu.Lock()
+	if attempts["ok"] != 1 || attempts["flaky"] != 2 || attempts["broken"] != 3 {
+		t.Errorf("unexpected attempts %v", attempts)
+	}
+	mu.Unlock()
+
+	dead, err := q.List(StateDead)
+	if err != nil || len(dead) != 1 || dead[0].Attempts != 3 || dead[0].LastError != "always fails" {
+		t.Fatalf("unexpected dead jobs %+v, %v", dead, err)
+	}
+
+	if _, err := q.Retry(dead[0].ID); err != nil {
+		t.Fatalf("failed to retry: %v", err)
+	}
+	waitFor(t, "the retried job to fail again", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return attempts["broken"] == 6
+	})
+	if _, err := q.Retry(999); err == nil {
+		t.Errorf("expected an error retrying a missing job")
+	}
+	if _, err := q.List("done"); err == nil {
+		t.Errorf("expected an error for an unknown state")
+	}
+
+	cancel()
+	<-done
+}
+
+func TestQueuePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.db")
+
+	// no handler is registered, so the job waits
+	q := openQueue(t, path, Options{})
+	if _, err := q.Enqueue("mirror", map[string]string{"email": "jane@example.com"}); err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+	q.Close()
+
+	q = openQueue(t, path, Options{})
+	defer q.Close()
+
+	ran := make(chan string, 1)
+	q.Register("mirror", func(ctx context.Context, payload json.RawMessage) error {
+		ran <- string(payload)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.Run(ctx)
+
+	select {
+	case payload := <-ran:
+		if payload != `{"email":"jane@example.com"}` {
+			t.Errorf("unexpected payload %s", payload)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("the job did not run after reopening")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	if d := Backoff(time.Second, 0); d != time.Second {
+		t.Errorf("expected 1s, got %s", d)
+	}
+	if d := Backoff(time.Second, 3); d != 8*time.Second {
+		t.Errorf("expected 8s, got %s", d)
+	}
+	if d := Backoff(time.Minute, 20); d != maxBackoff {
+		t.Errorf("expected the maximum, got %s", d)
+	}
+}
@@ -3,16 +3,15 @@ package linkedin
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
 
 	"github.com/rs/zerolog"
 
 	"github.com/Reskill-2022/volunteering/config"
+	"github.com/Reskill-2022/volunteering/identity"
 )
 
 type (
@@ -21,23 +20,13 @@ type (
 		ExpiresIn   int    `json:"expires_in"`
 	}
 
-	Service interface {
-		GetProfile(authCode, redirectURI string) (*GetProfileOutput, error)
-	}
+	Service = identity.Provider
 
 	GetProfileInput struct {
 		Email string
 	}
 
-	GetProfileOutput struct {
-		Email         string
-		Name          string
-		Photo         string
-		ProfileURL    string
-		Location      string
-		Phone         string
-		HasExperience bool
-	}
+	GetProfileOutput = identity.Profile
 
 	UserPhone struct {
 		Number string `json:"number"`
@@ -58,10 +47,15 @@ type (
 		} `json:"persons"`
 	}
 
+	Options = identity.Options
+
 	lkd struct {
 		logger       zerolog.Logger
 		clientID     string
 		clientSecret string
+		authURL      string
+		apiURL       string
+		client       *http.Client
 	}
 
 	EmailResponse struct {
@@ -94,12 +88,47 @@ type (
 	}
 )
 
-func New(logger zerolog.Logger, env config.Environment) Service {
-	return &lkd{
+const (
+	DefaultAuthURL = "https://www.linkedin.com"
+	DefaultAPIURL  = "https://api.linkedin.com"
+	// DefaultIssuer is the issuer of LinkedIn's id_tokens, below the auth
+	// host.
+	DefaultIssuer = DefaultAuthURL + "/oauth"
+)
+
+// New returns the Service for the LinkedIn flow selected in cfg.
+func New(logger zerolog.Logger, cfg config.LinkedIn) Service {
+	opts := Options{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		AuthURL:      cfg.AuthURL,
+		APIURL:       cfg.APIURL,
+	}
+	if cfg.Flow == config.FlowOIDC {
+		return NewOIDC(logger, opts)
+	}
+	return NewWithOptions(logger, opts)
+}
+
+func NewWithOptions(logger zerolog.Logger, opts Options) Service {
+	l := &lkd{
 		logger:       logger,
-		clientID:     env[config.ClientID],
-		clientSecret: env[config.ClientSecret],
+		clientID:     opts.ClientID,
+		clientSecret: opts.ClientSecret,
+		authURL:      strings.TrimSuffix(opts.AuthURL, "/"),
+		apiURL:       strings.TrimSuffix(opts.APIURL, "/"),
+		client:       opts.HTTPClient,
 	}
+	if l.authURL == "" {
+		l.authURL = DefaultAuthURL
+	}
+	if l.apiURL == "" {
+		l.apiURL = DefaultAPIURL
+	}
+	if l.client == nil {
+		l.client = http.DefaultClient
+	}
+	return l
 }
 
 func (l *lkd) GetProfile(authCode, redirectURI string) (*GetProfileOutput, error) {
@@ -107,7 +136,7 @@ func (l *lkd) GetProfile(authCode, redirectURI string) (*GetProfileOutput, error
 }
 
 func (l *lkd) getProfile(authCode, redirectURI string) (*GetProfileOutput, error) {
-	endpoint := "https://www.linkedin.com/oauth/v2/accessToken"
+	endpoint := l.authURL + "/oauth/v2/accessToken"
 
 	data := url.Values{}
 	data.Set("grant_type", "authorization_code")
@@ -123,21 +152,18 @@ func (l *lkd) getProfile(authCode, redirectURI string) (*GetProfileOutput, error
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := l.client.Do(req)
 	if err != nil {
 		l.logger.Err(err).Msg("Failed to do request")
 		return nil, fmt.Errorf("failed to get access token")
 	}
 
+	defer resp.Body.Close()
+
 	if resp.StatusCode != http.StatusOK {
 		l.logger.Err(fmt.Errorf("expected status code 200, got %d", resp.StatusCode)).Msg("Request failed")
-		_, err := io.Copy(os.Stdout, resp.Body)
-		if err != nil {
-			l.logger.Err(err).Msg("Failed to write response error")
-		}
 		return nil, fmt.Errorf("failed to get access token, not 200 ok")
 	}
-	defer resp.Body.Close()
 
 	var payload AccessTokenResponse
 
@@ -153,17 +179,17 @@ func (l *lkd) getProfile(authCode, redirectURI string) (*GetProfileOutput, error
 		return nil, fmt.Errorf("failed to unmarshal response body")
 	}
 
-	email, err := getUserEmail(payload.AccessToken)
+	email, err := l.getUserEmail(payload.AccessToken)
 	if err != nil {
 		return nil, err
 	}
 
-	fname, lname, picture, err := getUserProfile(payload.AccessToken)
+	fname, lname, picture, err := l.getUserProfile(payload.AccessToken)
 	if err != nil {
 		return nil, err
 	}
 
-	convPicture, err := getPhoto(picture, payload.AccessToken)
+	convPicture, err := l.getPhoto(picture, payload.AccessToken)
 	if err != nil {
 		l.logger.Debug().Msg(err.Error())
 	}
@@ -172,15 +198,17 @@ func (l *lkd) getProfile(authCode, redirectURI string) (*GetProfileOutput, error
 		picture = convPicture
 	}
 
-	return &GetProfileOutput{
+	profile := &GetProfileOutput{
 		Email: email,
 		Name:  fname + " " + lname,
 		Photo: picture,
-	}, nil
+	}
+	enrich(l.logger, l.client, l.apiURL, payload.AccessToken, profile)
+	return profile, nil
 }
 
-func getPhoto(urn, token string) (string, error) {
-	endpoint := "https://api.linkedin.com/v2/me?projection=(id,profilePicture(displayImage~digitalmediaAsset:playableStreams))"
+func (l *lkd) getPhoto(urn, token string) (string, error) {
+	endpoint := l.apiURL + "/v2/me?projection=(id,profilePicture(displayImage~digitalmediaAsset:playableStreams))"
 
 	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
 	if err != nil {
@@ -188,7 +216,7 @@ func getPhoto(urn, token string) (string, error) {
 	}
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := l.client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to do request")
 	}
@@ -215,8 +243,8 @@ func getPhoto(urn, token string) (string, error) {
 	return payload.ProfilePicture.DisplayImage.Elements[0].Identifiers[lenIdentifiers-1].Identifier, nil
 }
 
-func getUserProfile(token string) (string, string, string, error) {
-	endpoint := "https://api.linkedin.com/v2/me"
+func (l *lkd) getUserProfile(token string) (string, string, string, error) {
+	endpoint := l.apiURL + "/v2/me"
 
 	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
 	if err != nil {
@@ -224,7 +252,7 @@ func getUserProfile(token string) (string, string, string, error) {
 	}
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := l.client.Do(req)
 	if err != nil {
 		return "", "", "", fmt.Errorf("failed to do request")
 	}
@@ -243,8 +271,8 @@ func getUserProfile(token string) (string, string, string, error) {
 	return payload.LocalizedFirstName, payload.LocalizedLastName, payload.ProfilePicture.DisplayImage, nil
 }
 
-func getUserEmail(token string) (string, error) {
-	endpoint := "https://api.linkedin.com/v2/emailAddress?q=members&projection=(elements*(handle~))"
+func (l *lkd) getUserEmail(token string) (string, error) {
+	endpoint := l.apiURL + "/v2/emailAddress?q=members&projection=(elements*(handle~))"
 
 	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
 	if err != nil {
@@ -252,7 +280,7 @@ func getUserEmail(token string) (string, error) {
 	}
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := l.client.Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -262,7 +290,7 @@ func getUserEmail(token string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	fmt.Println(string(rawJSON))
+	l.logger.Debug().Int("status", resp.StatusCode).Int("bytes", len(rawJSON)).Msg("Got email address response")
 
 	var payload EmailResponse
 	err = json.Unmarshal(rawJSON, &payload)
@@ -0,0 +1,57 @@
+package linkedin
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Reskill-2022/volunteering/linkedin/linkedintest"
+)
+
+func TestGetProfile(t *testing.T) {
+	srv := linkedintest.NewServer(linkedintest.DefaultFixtures()...).Start()
+	defer srv.Close()
+
+	service := NewWithOptions(zerolog.Nop(), Options{
+		AuthURL:    srv.URL,
+		APIURL:     srv.URL,
+		HTTPClient: srv.Client(),
+	})
+
+	testCases := []struct {
+		code    string
+		wantErr bool
+		want    GetProfileOutput
+	}{
+		{code: "valid", want: GetProfileOutput{
+			Email: "jane.doe@example.com", Name: "Jane Doe", Photo: "https://media.example.com/jane.jpg",
+			ProfileURL: "https://www.linkedin.com/in/jane-doe", Location: "Austin, Texas", Phone: "+1 512 555 0100",
+			HasExperience: true, Positions: []string{"Engineering Manager", "Software Engineer"},
+		}},
+		{code: "no-photo", want: GetProfileOutput{Email: "john.roe@example.com", Name: "John Roe"}},
+		{code: "people-error", want: GetProfileOutput{Email: "people.error@example.com", Name: "People Error", Photo: "https://media.example.com/people.jpg"}},
+		{code: "photo-error", want: GetProfileOutput{Email: "photo.error@example.com", Name: "Photo Error", Photo: "urn:li:digitalmediaAsset:fake-photo-error"}},
+		{code: "unknown", wantErr: true},
+		{code: "token-error", wantErr: true},
+		{code: "profile-error", wantErr: true},
+		{code: "email-error", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		got, err := service.GetProfile(tc.code, "http://localhost/callback")
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("GetProfile(%s) expected error, got %+v", tc.code, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("GetProfile(%s) returned unexpected error: %v", tc.code, err)
+			continue
+		}
+		if !reflect.DeepEqual(*got, tc.want) {
+			t.Errorf("GetProfile(%s) = %+v, want %+v", tc.code, *got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,391 @@
+// Package linkedintest provides a fake LinkedIn OAuth and profile API for
+// local development and integration tests.
+package linkedintest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const keyID = "fake-linkedin-key"
+
+// Failure selects an endpoint that should fail for a Fixture.
+type Failure string
+
+const (
+	FailNone    Failure = ""
+	FailToken   Failure = "token"
+	FailProfile Failure = "profile"
+	FailEmail   Failure = "email"
+	FailPhoto   Failure = "photo"
+	FailIDToken Failure = "id_token"
+	// FailPeople fails the people lookup. Fixtures without a ProfileURL
+	// are denied it instead, like apps without partner access.
+	FailPeople Failure = "people"
+)
+
+// Fixture is a profile served by the fake server. It is selected by the
+// authorization code passed to the accessToken endpoint.
+type Fixture struct {
+	Code      string  `json:"code"`
+	FirstName string  `json:"first_name"`
+	LastName  string  `json:"last_name"`
+	Email     string  `json:"email"`
+	Photo     string  `json:"photo"`
+	Fail      Failure `json:"fail"`
+
+	// Served by the people lookup.
+	ProfileURL string   `json:"profile_url"`
+	Location   string   `json:"location"`
+	Phone      string   `json:"phone"`
+	Positions  []string `json:"positions"`
+}
+
+// DefaultFixtures covers the happy path and each failure scenario.
+func DefaultFixtures() []Fixture {
+	return []Fixture{
+		{
+			Code: "valid", FirstName: "Jane", LastName: "Doe", Email: "jane.doe@example.com", Photo: "https://media.example.com/jane.jpg",
+			ProfileURL: "https://www.linkedin.com/in/jane-doe", Location: "Austin, Texas", Phone: "+1 512 555 0100",
+			Positions: []string{"Engineering Manager", "Software Engineer"},
+		},
+		{Code: "no-photo", FirstName: "John", LastName: "Roe", Email: "john.roe@example.com"},
+		{Code: "token-error", FirstName: "Token", LastName: "Error", Email: "token.error@example.com", Fail: FailToken},
+		{Code: "profile-error", FirstName: "Profile", LastName: "Error", Email: "profile.error@example.com", Fail: FailProfile},
+		{Code: "email-error", FirstName: "Email", LastName: "Error", Email: "email.error@example.com", Fail: FailEmail},
+		{Code: "photo-error", FirstName: "Photo", LastName: "Error", Email: "photo.error@example.com", Photo: "https://media.example.com/photo.jpg", Fail: FailPhoto},
+		{Code: "id-token-error", FirstName: "Token", LastName: "Forged", Email: "forged@example.com", Fail: FailIDToken},
+		{Code: "people-error", FirstName: "People", LastName: "Error", Email: "people.error@example.com", Photo: "https://media.example.com/people.jpg", ProfileURL: "https://www.linkedin.com/in/people-error", Fail: FailPeople},
+	}
+}
+
+// LoadFixtures reads a JSON array of fixtures from path.
+func LoadFixtures(path string) ([]Fixture, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixtures []Fixture
+	if err := json.Unmarshal(raw, &fixtures); err != nil {
+		return nil, fmt.Errorf("failed to parse fixtures: %w", err)
+	}
+	return fixtures, nil
+}
+
+// Server implements the subset of the LinkedIn API used by the linkedin
+// package, for both the legacy and OpenID Connect flows. The auth and API
+// hosts are served from the same handler.
+type Server struct {
+	mu       sync.RWMutex
+	fixtures map[string]Fixture
+	tokens   map[string]string
+
+	signingKey *rsa.PrivateKey
+	forgeryKey *rsa.PrivateKey
+}
+
+func NewServer(fixtures ...Fixture) *Server {
+	s := &Server{
+		fixtures:   make(map[string]Fixture),
+		tokens:     make(map[string]string),
+		signingKey: mustGenerateKey(),
+		forgeryKey: mustGenerateKey(),
+	}
+	for _, f := range fixtures {
+		s.AddFixture(f)
+	}
+	return s
+}
+
+// AddFixture registers or replaces the fixture for f.Code.
+func (s *Server) AddFixture(f Fixture) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fixtures[f.Code] = f
+}
+
+// Start serves s on a local httptest.Server. Callers must Close it.
+func (s *Server) Start() *httptest.Server {
+	return httptest.NewServer(s)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/oauth/v2/accessToken":
+		s.accessToken(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/v2/me":
+		if strings.Contains(r.URL.Query().Get("projection"), "profilePicture") {
+			s.photo(w, r)
+			return
+		}
+		s.profile(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/v2/emailAddress":
+		s.email(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/v2/people":
+		s.people(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/v2/userinfo":
+		s.userInfo(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/oauth/openid/jwks":
+		s.jwks(w, r)
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "not found"})
+	}
+}
+
+func (s *Server) accessToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	code := r.PostForm.Get("code")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.fixtures[code]
+	if !ok || f.Fail == FailToken || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error":             "invalid_grant",
+			"error_description": "The authorization code is invalid or has expired",
+		})
+		return
+	}
+
+	idToken, err := s.idToken(f, issuer(r), r.PostForm.Get("client_id"))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	token := "token-" + code
+	s.tokens[token] = code
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": token,
+		"expires_in":   5184000,
+		"id_token":     idToken,
+	})
+}
+
+func (s *Server) idToken(f Fixture, iss, audience string) (string, error) {
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            iss,
+		"aud":            audience,
+		"sub":            subject(f),
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"name":           strings.TrimSpace(f.FirstName + " " + f.LastName),
+		"given_name":     f.FirstName,
+		"family_name":    f.LastName,
+		"picture":        f.Photo,
+		"email":          f.Email,
+		"email_verified": f.Fail != FailEmail,
+		"locale":         "en_US",
+	})
+	token.Header["kid"] = keyID
+
+	key := s.signingKey
+	if f.Fail == FailIDToken {
+		key = s.forgeryKey
+	}
+	return token.SignedString(key)
+}
+
+func (s *Server) userInfo(w http.ResponseWriter, r *http.Request) {
+	f, ok := s.authorize(w, r)
+	if !ok {
+		return
+	}
+	if f.Fail == FailProfile {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"message": "internal error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"sub":            subject(f),
+		"name":           strings.TrimSpace(f.FirstName + " " + f.LastName),
+		"given_name":     f.FirstName,
+		"family_name":    f.LastName,
+		"picture":        f.Photo,
+		"email":          f.Email,
+		"email_verified": f.Fail != FailEmail,
+		"locale":         "en_US",
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := s.signingKey.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": keyID,
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func (s *Server) profile(w http.ResponseWriter, r *http.Request) {
+	f, ok := s.authorize(w, r)
+	if !ok {
+		return
+	}
+	if f.Fail == FailProfile {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"message": "internal error"})
+		return
+	}
+
+	body := map[string]interface{}{
+		"localizedFirstName": f.FirstName,
+		"localizedLastName":  f.LastName,
+	}
+	if f.Photo != "" {
+		body["profilePicture"] = map[string]string{"displayImage": photoURN(f)}
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+func (s *Server) photo(w http.ResponseWriter, r *http.Request) {
+	f, ok := s.authorize(w, r)
+	if !ok {
+		return
+	}
+	if f.Fail == FailPhoto {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"message": "internal error"})
+		return
+	}
+
+	elements := []interface{}{}
+	if f.Photo != "" {
+		elements = append(elements, map[string]interface{}{
+			"identifiers": []map[string]string{
+				{"identifier": f.Photo + "?size=100"},
+				{"identifier": f.Photo},
+			},
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id": "fake-" + f.Code,
+		"profilePicture": map[string]interface{}{
+			"displayImage~": map[string]interface{}{
+				"elements": elements,
+			},
+		},
+	})
+}
+
+func (s *Server) email(w http.ResponseWriter, r *http.Request) {
+	f, ok := s.authorize(w, r)
+	if !ok {
+		return
+	}
+
+	elements := []interface{}{}
+	if f.Fail != FailEmail {
+		elements = append(elements, map[string]interface{}{
+			"handle":  "urn:li:emailAddress:" + f.Code,
+			"handle~": map[string]string{"emailAddress": f.Email},
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"elements": elements,
+	})
+}
+
+func (s *Server) people(w http.ResponseWriter, r *http.Request) {
+	f, ok := s.authorize(w, r)
+	if !ok {
+		return
+	}
+	switch {
+	case f.Fail == FailPeople:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"message": "internal error"})
+		return
+	case f.ProfileURL == "":
+		writeJSON(w, http.StatusForbidden, map[string]string{"message": "Not enough permissions to access: people.GET"})
+		return
+	}
+
+	positions := []map[string]string{}
+	for _, title := range f.Positions {
+		positions = append(positions, map[string]string{"title": title})
+	}
+	person := map[string]interface{}{
+		"displayName":  strings.TrimSpace(f.FirstName + " " + f.LastName),
+		"phoneNumbers": []map[string]string{},
+		"location":     f.Location,
+		"photoUrl":     f.Photo,
+		"linkedInUrl":  f.ProfileURL,
+		"positions":    map[string]interface{}{"positionHistory": positions},
+	}
+	if f.Phone != "" {
+		person["phoneNumbers"] = []map[string]string{{"number": f.Phone}}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"persons": []interface{}{person},
+	})
+}
+
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) (Fixture, bool) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	code, ok := s.tokens[token]
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Invalid access token"})
+		return Fixture{}, false
+	}
+	return s.fixtures[code], true
+}
+
+func subject(f Fixture) string {
+	return "fake-" + f.Code
+}
+
+// issuer is the id_token issuer, below the auth URL as LinkedIn's is.
+func issuer(r *http.Request) string {
+	if r.TLS != nil {
+		return "https://" + r.Host + "/oauth"
+	}
+	return "http://" + r.Host + "/oauth"
+}
+
+func mustGenerateKey() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(fmt.Sprintf("linkedintest: failed to generate signing key: %v", err))
+	}
+	return key
+}
+
+func photoURN(f Fixture) string {
+	return "urn:li:digitalmediaAsset:fake-" + f.Code
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,334 @@
+package linkedin
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/rs/zerolog"
+)
+
+// jwksRefreshInterval is the minimum time between two requests for the JWKS.
+const jwksRefreshInterval = 5 * time.Minute
+
+type (
+	OIDCTokenResponse struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+		IDToken     string `json:"id_token"`
+	}
+
+	// IDTokenClaims are the claims LinkedIn includes in the id_token and
+	// returns from the userinfo endpoint.
+	IDTokenClaims struct {
+		jwt.StandardClaims
+		Name          string `json:"name"`
+		GivenName     string `json:"given_name"`
+		FamilyName    string `json:"family_name"`
+		Picture       string `json:"picture"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Locale        string `json:"locale"`
+	}
+
+	UserInfoResponse struct {
+		Sub           string `json:"sub"`
+		Name          string `json:"name"`
+		GivenName     string `json:"given_name"`
+		FamilyName    string `json:"family_name"`
+		Picture       string `json:"picture"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Locale        string `json:"locale"`
+	}
+
+	JWK struct {
+		Kid string `json:"kid"`
+		Kty string `json:"kty"`
+		Alg string `json:"alg"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	}
+
+	JWKSResponse struct {
+		Keys []JWK `json:"keys"`
+	}
+
+	oidc struct {
+		logger       zerolog.Logger
+		clientID     string
+		clientSecret string
+		authURL      string
+		apiURL       string
+		issuer       string
+		client       *http.Client
+
+		mu   sync.RWMutex
+		keys map[string]*rsa.PublicKey
+		// fetched is when the JWKS was last requested.
+		fetched time.Time
+	}
+)
+
+// NewOIDC returns a Service that uses the "Sign In with LinkedIn using
+// OpenID Connect" flow: one token exchange yielding a signed id_token, which
+// is verified against LinkedIn's JWKS, and one call to /v2/userinfo.
+func NewOIDC(logger zerolog.Logger, opts Options) Service {
+	o := &oidc{
+		logger:       logger,
+		clientID:     opts.ClientID,
+		clientSecret: opts.ClientSecret,
+		authURL:      strings.TrimSuffix(opts.AuthURL, "/"),
+		apiURL:       strings.TrimSuffix(opts.APIURL, "/"),
+		issuer:       opts.Issuer,
+		client:       opts.HTTPClient,
+		keys:         make(map[string]*rsa.PublicKey),
+	}
+	if o.authURL == "" {
+		o.authURL = DefaultAuthURL
+	}
+	if o.apiURL == "" {
+		o.apiURL = DefaultAPIURL
+	}
+	if o.client == nil {
+		o.client = http.DefaultClient
+	}
+	if o.issuer == "" {
+		// DefaultIssuer for the default auth host
+		o.issuer = o.authURL + "/oauth"
+	}
+	return o
+}
+
+func (o *oidc) GetProfile(authCode, redirectURI string) (*GetProfileOutput, error) {
+	token, err := o.exchangeCode(authCode, redirectURI)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := o.verifyIDToken(token.IDToken)
+	if err != nil {
+		o.logger.Err(err).Msg("Failed to verify id_token")
+		return nil, fmt.Errorf("failed to verify id_token")
+	}
+
+	info, err := o.getUserInfo(token.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	if info.Sub != claims.Subject {
+		return nil, fmt.Errorf("userinfo subject does not match id_token")
+	}
+
+	if !claims.EmailVerified || claims.Email == "" {
+		return nil, fmt.Errorf("linkedin email address is not verified")
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = strings.TrimSpace(claims.GivenName + " " + claims.FamilyName)
+	}
+	photo := info.Picture
+	if photo == "" {
+		photo = claims.Picture
+	}
+
+	// the people lookup is left to the legacy flow, which is granted partner
+	// access; OpenID Connect apps only read the basic profile
+	return &GetProfileOutput{
+		Email: claims.Email,
+		Name:  name,
+		Photo: photo,
+	}, nil
+}
+
+func (o *oidc) exchangeCode(authCode, redirectURI string) (*OIDCTokenResponse, error) {
+	data := url.Values{}
+	data.Set("grant_type", "authorization_code")
+	data.Set("code", authCode)
+	data.Set("client_id", o.clientID)
+	data.Set("client_secret", o.clientSecret)
+	data.Set("redirect_uri", redirectURI)
+
+	req, err := http.NewRequest(http.MethodPost, o.authURL+"/oauth/v2/accessToken", strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		o.logger.Err(err).Msg("Failed to do request")
+		return nil, fmt.Errorf("failed to get access token")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		o.logger.Error().Msgf("Token exchange failed with status %d: %s", resp.StatusCode, body)
+		return nil, fmt.Errorf("failed to get access token, not 200 ok")
+	}
+
+	var payload OIDCTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response body")
+	}
+	if payload.IDToken == "" {
+		return nil, fmt.Errorf("token response has no id_token, is the openid scope requested?")
+	}
+
+	return &payload, nil
+}
+
+func (o *oidc) verifyIDToken(raw string) (*IDTokenClaims, error) {
+	claims := &IDTokenClaims{}
+
+	_, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return o.publicKey(kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !claims.VerifyIssuer(o.issuer, true) {
+		return nil, fmt.Errorf("unexpected issuer '%s'", claims.Issuer)
+	}
+	if !claims.VerifyAudience(o.clientID, true) {
+		return nil, fmt.Errorf("unexpected audience '%s'", claims.Audience)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("id_token has no subject")
+	}
+
+	return claims, nil
+}
+
+// publicKey returns the signing key for kid, refreshing the JWKS when the key
+// is not cached so that LinkedIn's key rotation is picked up. The JWKS is
+// requested at most once every jwksRefreshInterval; until then unknown kids
+// are rejected from the cache, so forged tokens cannot make us hammer
+// LinkedIn.
+func (o *oidc) publicKey(kid string) (*rsa.PublicKey, error) {
+	o.mu.RLock()
+	key, ok := o.keys[kid]
+	o.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	o.mu.Lock()
+	key, ok = o.keys[kid]
+	if ok {
+		o.mu.Unlock()
+		return key, nil
+	}
+	if now := time.Now(); now.Sub(o.fetched) >= jwksRefreshInterval {
+		o.fetched = now
+		ok = true
+	}
+	o.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("no signing key found for kid '%s'", kid)
+	}
+
+	if err := o.refreshKeys(); err != nil {
+		return nil, err
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	key, ok = o.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("no signing key found for kid '%s'", kid)
+	}
+	return key, nil
+}
+
+func (o *oidc) refreshKeys() error {
+	resp, err := o.client.Get(o.authURL + "/oauth/openid/jwks")
+	if err != nil {
+		return fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch jwks, got status %d", resp.StatusCode)
+	}
+
+	var payload JWKSResponse
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return fmt.Errorf("failed to unmarshal jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(payload.Keys))
+	for _, k := range payload.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		key, err := k.RSAPublicKey()
+		if err != nil {
+			o.logger.Err(err).Msgf("Skipping invalid jwk '%s'", k.Kid)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+
+	o.mu.Lock()
+	o.keys = keys
+	o.mu.Unlock()
+	return nil
+}
+
+func (o *oidc) getUserInfo(token string) (*UserInfoResponse, error) {
+	req, err := http.NewRequest(http.MethodGet, o.apiURL+"/v2/userinfo", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request")
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to do request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get userinfo, not ok")
+	}
+
+	var payload UserInfoResponse
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response body")
+	}
+	return &payload, nil
+}
+
+// RSAPublicKey decodes the modulus and exponent of an RSA JWK.
+func (k JWK) RSAPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
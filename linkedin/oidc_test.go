@@ -0,0 +1,130 @@
+package linkedin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Reskill-2022/volunteering/linkedin/linkedintest"
+)
+
+func TestOIDCGetProfile(t *testing.T) {
+	srv := linkedintest.NewServer(linkedintest.DefaultFixtures()...).Start()
+	defer srv.Close()
+
+	service := NewOIDC(zerolog.Nop(), Options{
+		ClientID:   "client-id",
+		AuthURL:    srv.URL,
+		APIURL:     srv.URL,
+		HTTPClient: srv.Client(),
+	})
+
+	testCases := []struct {
+		code    string
+		wantErr bool
+		want    GetProfileOutput
+	}{
+		// no people lookup, unlike the legacy flow
+		{code: "valid", want: GetProfileOutput{Email: "jane.doe@example.com", Name: "Jane Doe", Photo: "https://media.example.com/jane.jpg"}},
+		{code: "no-photo", want: GetProfileOutput{Email: "john.roe@example.com", Name: "John Roe"}},
+		{code: "people-error", want: GetProfileOutput{Email: "people.error@example.com", Name: "People Error", Photo: "https://media.example.com/people.jpg"}},
+		{code: "unknown", wantErr: true},
+		{code: "token-error", wantErr: true},
+		{code: "profile-error", wantErr: true},
+		{code: "email-error", wantErr: true},
+		{code: "id-token-error", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		got, err := service.GetProfile(tc.code, "http://localhost/callback")
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("GetProfile(%s) expected error, got %+v", tc.code, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("GetProfile(%s) returned unexpected error: %v", tc.code, err)
+			continue
+		}
+		if !reflect.DeepEqual(*got, tc.want) {
+			t.Errorf("GetProfile(%s) = %+v, want %+v", tc.code, *got, tc.want)
+		}
+	}
+}
+
+func TestOIDCRejectsForeignAudience(t *testing.T) {
+	srv := linkedintest.NewServer(linkedintest.DefaultFixtures()...).Start()
+	defer srv.Close()
+
+	service := NewOIDC(zerolog.Nop(), Options{
+		ClientID:   "client-id",
+		AuthURL:    srv.URL,
+		APIURL:     srv.URL,
+		HTTPClient: srv.Client(),
+	}).(*oidc)
+
+	token, err := service.exchangeCode("valid", "http://localhost/callback")
+	if err != nil {
+		t.Fatalf("unexpected error exchanging code: %v", err)
+	}
+
+	service.clientID = "someone-else"
+	if _, err := service.verifyIDToken(token.IDToken); err == nil {
+		t.Errorf("expected id_token issued to another client to be rejected")
+	}
+}
+
+func TestOIDCIssuer(t *testing.T) {
+	if got := NewOIDC(zerolog.Nop(), Options{}).(*oidc).issuer; got != DefaultIssuer {
+		t.Errorf("default issuer = %s, want %s", got, DefaultIssuer)
+	}
+
+	srv := linkedintest.NewServer(linkedintest.DefaultFixtures()...).Start()
+	defer srv.Close()
+
+	service := NewOIDC(zerolog.Nop(), Options{
+		ClientID:   "client-id",
+		AuthURL:    srv.URL,
+		APIURL:     srv.URL,
+		Issuer:     DefaultIssuer,
+		HTTPClient: srv.Client(),
+	})
+	if _, err := service.GetProfile("valid", "http://localhost/callback"); err == nil {
+		t.Errorf("expected id_token from another issuer to be rejected")
+	}
+}
+
+func TestOIDCRefreshInterval(t *testing.T) {
+	var fetches int
+	lkd := linkedintest.NewServer(linkedintest.DefaultFixtures()...)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth/openid/jwks" {
+			fetches++
+		}
+		lkd.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	service := NewOIDC(zerolog.Nop(), Options{AuthURL: srv.URL, APIURL: srv.URL, HTTPClient: srv.Client()}).(*oidc)
+
+	for i := 0; i < 3; i++ {
+		if _, err := service.publicKey("unknown"); err == nil {
+			t.Fatalf("expected unknown kid to be rejected")
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("unknown kids fetched the jwks %d times, want 1", fetches)
+	}
+
+	service.fetched = service.fetched.Add(-jwksRefreshInterval)
+	if _, err := service.publicKey("unknown"); err == nil {
+		t.Fatalf("expected unknown kid to be rejected")
+	}
+	if fetches != 2 {
+		t.Errorf("jwks fetched %d times once the interval passed, want 2", fetches)
+	}
+}
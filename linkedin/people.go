@@ -0,0 +1,80 @@
+package linkedin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// errScopeDenied is returned by getFullProfile when the app has not been
+// granted access to the people lookup.
+var errScopeDenied = fmt.Errorf("people lookup not permitted for this app")
+
+// enrich adds the public profile URL, location, phone number and position
+// history to p. They come from the people lookup, which LinkedIn only opens
+// to partner apps, so sign-in never fails because of it: without access, or
+// on any error, p is left as it is.
+func enrich(logger zerolog.Logger, client *http.Client, apiURL, token string, p *GetProfileOutput) {
+	full, err := getFullProfile(client, apiURL, token, p.Email)
+	if err == errScopeDenied {
+		logger.Debug().Msg("LinkedIn: people lookup not permitted, using the basic profile")
+		return
+	}
+	if err != nil {
+		logger.Warn().Err(err).Msgf("LinkedIn: failed to look up the full profile of %s", p.Email)
+		return
+	}
+	if len(full.Persons) == 0 {
+		return
+	}
+
+	person := full.Persons[0]
+	p.ProfileURL = person.LinkedInURL
+	p.Location = person.Location
+	for _, phone := range person.PhoneNumbers {
+		if phone.Number != "" {
+			p.Phone = phone.Number
+			break
+		}
+	}
+	for _, position := range person.Positions.PositionHistory {
+		if title := strings.TrimSpace(position.Title); title != "" {
+			p.Positions = append(p.Positions, title)
+		}
+	}
+	p.HasExperience = len(person.Positions.PositionHistory) > 0
+}
+
+func getFullProfile(client *http.Client, apiURL, token, email string) (*UserProfileResponse, error) {
+	endpoint := apiURL + "/v2/people?q=email&email=" + url.QueryEscape(email)
+
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request")
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, errScopeDenied
+	default:
+		return nil, fmt.Errorf("failed to get full user profile, got %d", resp.StatusCode)
+	}
+
+	var payload UserProfileResponse
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response body")
+	}
+	return &payload, nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 
@@ -9,6 +10,9 @@ import (
 
 	"github.com/Reskill-2022/volunteering/config"
 	"github.com/Reskill-2022/volunteering/controllers"
+	"github.com/Reskill-2022/volunteering/github"
+	"github.com/Reskill-2022/volunteering/google"
+	"github.com/Reskill-2022/volunteering/identity"
 	"github.com/Reskill-2022/volunteering/linkedin"
 	"github.com/Reskill-2022/volunteering/repository"
 	"github.com/Reskill-2022/volunteering/server"
@@ -24,41 +28,47 @@ func main() {
 		log.Println(err)
 	}
 
-	env, err := config.New()
+	if len(os.Args) > 1 {
+		if err := runCommand(appLogger, os.Args[1], os.Args[2:]); err != nil {
+			appLogger.Fatal().Err(err).Msgf("Command '%s' failed", os.Args[1])
+		}
+		return
+	}
+
+	cfg, err := config.New()
 	if err != nil {
 		appLogger.Fatal().Err(err).Msg("Failed to load configs")
 	}
 
-	writeSAs(appLogger, env)
-
 	cts := controllers.NewContainer(appLogger)
-	rc := repository.NewContainer(appLogger)
-	service := linkedin.New(appLogger, env)
+	rc, err := repository.NewContainer(appLogger, cfg)
+	if err != nil {
+		appLogger.Fatal().Err(err).Msg("Failed to initialise storage")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rc.Start(ctx)
 
-	if err := server.Start(appLogger, env, cts, rc, service); err != nil {
+	providers := newProviders(appLogger, cfg)
+
+	if err := server.Start(appLogger, cfg, cts, rc, providers); err != nil {
 		appLogger.Fatal().Err(err).Msg("Failed to start server")
 	}
 }
 
-func writeSAs(appLogger zerolog.Logger, env config.Environment) {
-	sa1, ok := env[config.ServiceAccount1]
-	if !ok {
-		appLogger.Fatal().Msg("Service account 1 not found")
-	}
-
-	sa2, ok := env[config.ServiceAccount2]
-	if !ok {
-		appLogger.Fatal().Msg("Service account 2 not found")
+// newProviders returns LinkedIn plus every other identity provider that has
+// client credentials configured.
+func newProviders(appLogger zerolog.Logger, cfg *config.Config) identity.Providers {
+	providers := identity.Providers{
+		identity.LinkedIn: linkedin.New(appLogger, cfg.LinkedIn),
 	}
-
-	sa1OutFile := "service-account-1.json"
-	sa2OutFile := "service-account-2.json"
-
-	if err := os.WriteFile(sa1OutFile, []byte(sa1), 0644); err != nil {
-		appLogger.Fatal().Err(err).Msg("Failed to write service account 1")
+	if cfg.Google.ClientID != "" {
+		providers[identity.Google] = google.New(appLogger, cfg.Google)
 	}
-
-	if err := os.WriteFile(sa2OutFile, []byte(sa2), 0644); err != nil {
-		appLogger.Fatal().Err(err).Msg("Failed to write service account 2")
+	if cfg.GitHub.ClientID != "" {
+		providers[identity.GitHub] = github.New(appLogger, cfg.GitHub)
 	}
+	appLogger.Info().Msgf("Sign-in providers enabled: %v", providers.Names())
+	return providers
 }
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// Audit actions.
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+)
+
+// AuditEntry records one change to a volunteer. Entries are only ever
+// appended.
+type AuditEntry struct {
+	Email  string `json:"email" firestore:"email"`
+	Action string `json:"action" firestore:"action"`
+	// Actor and Role identify who made the change, see audit.Actor.
+	Actor    string    `json:"actor" firestore:"actor"`
+	Role     string    `json:"role" firestore:"role"`
+	Endpoint string    `json:"endpoint,omitempty" firestore:"endpoint"`
+	Changes  []Change  `json:"changes" firestore:"changes"`
+	At       time.Time `json:"at" firestore:"at"`
+}
+
+// Change is the value of one field before and after a change. Values are
+// as they appear in the API, nil when absent.
+type Change struct {
+	Field  string      `json:"field" firestore:"field"`
+	Before interface{} `json:"before" firestore:"before"`
+	After  interface{} `json:"after" firestore:"after"`
+}
@@ -0,0 +1,57 @@
+package model
+
+import "time"
+
+// Question types.
+const (
+	FieldText        = "text"
+	FieldTextarea    = "textarea"
+	FieldBoolean     = "boolean"
+	FieldNumber      = "number"
+	FieldSelect      = "select"
+	FieldMultiselect = "multiselect"
+)
+
+// Form is one version of the application form. Versions are never changed
+// once saved, so answers can always be read against the form they were
+// submitted with.
+type Form struct {
+	Version   int       `json:"version" firestore:"version"`
+	Fields    []Field   `json:"fields" firestore:"fields"`
+	CreatedAt time.Time `json:"created_at" firestore:"created_at"`
+	CreatedBy string    `json:"created_by,omitempty" firestore:"created_by"`
+}
+
+// Field is one question of a Form. The choices of select and multiselect
+// questions are either listed in Options or taken from the taxonomy list
+// named by OptionList.
+type Field struct {
+	Name       string     `json:"name" firestore:"name"`
+	Label      string     `json:"label" firestore:"label"`
+	Type       string     `json:"type" firestore:"type"`
+	Required   bool       `json:"required" firestore:"required"`
+	Help       string     `json:"help,omitempty" firestore:"help"`
+	Options    []Option   `json:"options,omitempty" firestore:"options"`
+	OptionList string     `json:"option_list,omitempty" firestore:"option_list"`
+	ShowIf     *Condition `json:"show_if,omitempty" firestore:"show_if"`
+	// Step groups the field into a page of a multi-step form.
+	Step string `json:"step,omitempty" firestore:"step"`
+}
+
+// Draft is an application in progress. Its answers are checked one by one as
+// they are saved, and as a whole when it is submitted.
+type Draft struct {
+	FormVersion int                    `json:"form_version" firestore:"form_version"`
+	Answers     map[string]interface{} `json:"answers" firestore:"answers"`
+	// Steps maps each step of the form to whether it is complete.
+	Steps     map[string]bool `json:"steps" firestore:"steps"`
+	UpdatedAt time.Time       `json:"updated_at" firestore:"updated_at"`
+}
+
+// Condition shows a Field only when the answer to an earlier field is one
+// of Values. Booleans are compared as "true" or "false", and a multiselect
+// answer matches if any of its choices does.
+type Condition struct {
+	Field  string   `json:"field" firestore:"field"`
+	Values []string `json:"values" firestore:"values"`
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// StringList is a multi-valued answer, such as the volunteer areas. It is
+// stored as an array and encoded as a JSON array, but also decodes the
+// comma-separated strings older clients send.
+type StringList []string
+
+// ParseStringList splits a comma-separated list, dropping empty values.
+func ParseStringList(list string) StringList {
+	var values StringList
+	for _, v := range strings.Split(list, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// Contains reports whether value is one of l.
+func (l StringList) Contains(value string) bool {
+	for _, v := range l {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (l StringList) String() string {
+	return strings.Join(l, ",")
+}
+
+func (l StringList) MarshalJSON() ([]byte, error) {
+	if l == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]string(l))
+}
+
+func (l *StringList) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		// leave missing answers nil
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*l = ParseStringList(s)
+		if *l == nil {
+			*l = StringList{}
+		}
+		return nil
+	}
+
+	var values []string
+	if err := json.Unmarshal(b, &values); err != nil {
+		return err
+	}
+	*l = values
+	return nil
+}
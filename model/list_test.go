@@ -0,0 +1,47 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestStringListJSON(t *testing.T) {
+	testCases := []struct {
+		input   string
+		want    string
+		wantNil bool
+		wantErr bool
+	}{
+		{`["Mentoring", "Design, UX"]`, "[Mentoring Design, UX]", false, false},
+		{`"Mentoring, Design,,"`, "[Mentoring Design]", false, false},
+		{`""`, "[]", false, false},
+		{`[]`, "[]", false, false},
+		{`null`, "[]", true, false},
+		{`42`, "", false, true},
+	}
+
+	for _, tc := range testCases {
+		var v struct {
+			Areas StringList `json:"areas"`
+		}
+		err := json.Unmarshal([]byte(`{"areas": `+tc.input+`}`), &v)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("Unmarshal(%s) error = %v, wantErr %t", tc.input, err, tc.wantErr)
+			continue
+		}
+		if tc.wantErr {
+			continue
+		}
+		if got := fmt.Sprint([]string(v.Areas)); got != tc.want || (v.Areas == nil) != tc.wantNil {
+			t.Errorf("Unmarshal(%s) = %s (nil %t), want %s (nil %t)", tc.input, got, v.Areas == nil, tc.want, tc.wantNil)
+		}
+	}
+
+	out, err := json.Marshal(struct {
+		Areas StringList `json:"areas"`
+	}{})
+	if err != nil || string(out) != `{"areas":[]}` {
+		t.Errorf("expected nil list to encode as [], got %s (%v)", out, err)
+	}
+}
@@ -10,17 +10,55 @@ type User struct {
 	FirstName string `json:"first_name" firestore:"first_name"`
 	LastName  string `json:"last_name" firestore:"last_name"`
 	Photo     string `json:"photo" firestore:"photo"`
+	// Provider is the identity provider that verified Email. Accounts
+	// created before multi-provider sign-in have an empty Provider and were
+	// verified by LinkedIn.
+	Provider string `json:"provider" firestore:"provider"`
+	// Location, HasExperience and Positions are read from the identity
+	// provider at sign-up, when it shares them.
+	Location      string     `json:"location" firestore:"location"`
+	HasExperience bool       `json:"has_experience" firestore:"has_experience"`
+	Positions     StringList `json:"positions" firestore:"positions"`
 
 	// Extras
-	State             string `json:"state" firestore:"state"`
-	Organization      string `json:"organization" firestore:"organization"`
-	YearsOfExperience string `json:"years_of_experience" firestore:"years_of_experience"`
-	VolunteerAreas    string `json:"volunteer_areas" firestore:"volunteer_areas"`
-	VolunteerMeans    string `json:"volunteer_means" firestore:"volunteer_means"`
-	Convicted         bool   `json:"convicted" firestore:"convicted"`
-	Representation    string `json:"representation" firestore:"representation"`
-	ProvidedName      string `json:"provided_name" firestore:"provided_name"`
+	State             string     `json:"state" firestore:"state"`
+	Organization      string     `json:"organization" firestore:"organization"`
+	YearsOfExperience string     `json:"years_of_experience" firestore:"years_of_experience"`
+	VolunteerAreas    StringList `json:"volunteer_areas" firestore:"volunteer_areas"`
+	VolunteerMeans    StringList `json:"volunteer_means" firestore:"volunteer_means"`
+	Convicted         bool       `json:"convicted" firestore:"convicted"`
+	Representation    string     `json:"representation" firestore:"representation"`
+	ProvidedName      string     `json:"provided_name" firestore:"provided_name"`
+	// ProfileURL is the volunteer's LinkedIn profile, normalised by
+	// validation.LinkedInURL.
+	ProfileURL string     `json:"profile_url" firestore:"profile_url"`
+	Industries StringList `json:"industries" firestore:"industries"`
+
+	// Answers holds every answer of the application, keyed by field name,
+	// as validated against version FormVersion of the form. The answers
+	// above are copied out of it for filtering and export.
+	Answers     map[string]interface{} `json:"answers,omitempty" firestore:"answers"`
+	FormVersion int                    `json:"form_version,omitempty" firestore:"form_version"`
+	// Draft holds the answers saved before the application is submitted.
+	Draft *Draft `json:"draft,omitempty" firestore:"draft"`
 
 	Enrolled  bool      `json:"enrolled" firestore:"enrolled"`
 	CreatedAt time.Time `json:"created_at" firestore:"created_at"`
+
+	// Status is the application's place in the review workflow, see
+	// package review. Applications submitted before the workflow existed
+	// have none until they are first reviewed.
+	Status      string     `json:"status,omitempty" firestore:"status"`
+	SubmittedAt *time.Time `json:"submitted_at,omitempty" firestore:"submitted_at"`
+	// Reviews is the status history, oldest first.
+	Reviews []Review `json:"reviews,omitempty" firestore:"reviews"`
+
+	// Deactivated accounts can no longer sign in or apply.
+	Deactivated bool `json:"deactivated" firestore:"deactivated"`
+}
+
+// Option is one allowed answer to a multiple-choice question.
+type Option struct {
+	Value string `json:"value" firestore:"value"`
+	Label string `json:"label" firestore:"label"`
 }
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// Application statuses.
+const (
+	StatusSubmitted   = "submitted"
+	StatusUnderReview = "under_review"
+	StatusApproved    = "approved"
+	StatusRejected    = "rejected"
+	StatusWithdrawn   = "withdrawn"
+)
+
+// Review records one change of an application's status.
+type Review struct {
+	From string `json:"from,omitempty" firestore:"from"`
+	To   string `json:"to" firestore:"to"`
+	Note string `json:"note,omitempty" firestore:"note"`
+	// Reviewer is the admin who made the change, or the volunteer's email
+	// when they submitted or withdrew the application themselves.
+	Reviewer string    `json:"reviewer" firestore:"reviewer"`
+	Role     string    `json:"role" firestore:"role"`
+	At       time.Time `json:"at" firestore:"at"`
+}
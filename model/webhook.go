@@ -0,0 +1,31 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Delivery is one event sent, or being sent, to one webhook.
+type Delivery struct {
+	ID      string `json:"id" firestore:"id"`
+	Webhook string `json:"webhook" firestore:"webhook"`
+	Event   string `json:"event" firestore:"event"`
+	// Payload is the signed request body.
+	Payload json.RawMessage `json:"payload" firestore:"payload"`
+	// Status is DeliveryFailed while a failed delivery waits to be retried,
+	// and once it has been given up on.
+	Status       string     `json:"status" firestore:"status"`
+	Attempts     int        `json:"attempts" firestore:"attempts"`
+	ResponseCode int        `json:"response_code,omitempty" firestore:"response_code"`
+	LastError    string     `json:"last_error,omitempty" firestore:"last_error"`
+	CreatedAt    time.Time  `json:"created_at" firestore:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" firestore:"updated_at"`
+	DeliveredAt  *time.Time `json:"delivered_at,omitempty" firestore:"delivered_at"`
+}
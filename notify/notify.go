@@ -0,0 +1,113 @@
+// Package notify emails volunteers when their account is created, their
+// application is received and a decision is made on it. Emails are sent in
+// the background and retried, so that a slow or failing mail server never
+// fails a request.
+package notify
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Reskill-2022/volunteering/config"
+	"github.com/Reskill-2022/volunteering/model"
+)
+
+// MaxAttempts bounds how many times a message is sent before it is dropped.
+const MaxAttempts = 5
+
+// sendTimeout bounds each attempt.
+const sendTimeout = 30 * time.Second
+
+// Notifier sends notifications through a Sender. A nil Notifier, or one
+// without a Sender, sends nothing.
+type Notifier struct {
+	logger        zerolog.Logger
+	sender        Sender
+	from          string
+	retryInterval time.Duration
+
+	wg sync.WaitGroup
+}
+
+func New(logger zerolog.Logger, sender Sender, from string, retryInterval time.Duration) *Notifier {
+	return &Notifier{
+		logger:        logger,
+		sender:        sender,
+		from:          from,
+		retryInterval: retryInterval,
+	}
+}
+
+// FromConfig returns a Notifier using the configured mail driver.
+func FromConfig(logger zerolog.Logger, cfg config.Mail) (*Notifier, error) {
+	sender, err := NewSender(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return New(logger, sender, cfg.From, cfg.RetryInterval), nil
+}
+
+// Notify sends the kind of notification to user in the background.
+func (n *Notifier) Notify(kind string, user model.User) {
+	if n == nil || n.sender == nil || user.Email == "" {
+		return
+	}
+
+	subject, body, err := render(kind, user)
+	if err != nil {
+		n.logger.Err(err).Msgf("Notify: failed to render %s for %s", kind, user.Email)
+		return
+	}
+	msg := Message{From: n.from, To: user.Email, Subject: subject, Body: body}
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		n.deliver(kind, msg)
+	}()
+}
+
+// deliver sends msg, waiting twice as long after each failed attempt.
+func (n *Notifier) deliver(kind string, msg Message) {
+	wait := n.retryInterval
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		err := n.sender.Send(ctx, msg)
+		cancel()
+		if err == nil {
+			n.logger.Debug().Msgf("Notify: sent %s to %s", kind, msg.To)
+			return
+		}
+		if attempt == MaxAttempts {
+			n.logger.Err(err).Msgf("Notify: giving up on %s to %s after %d attempts", kind, msg.To, attempt)
+			return
+		}
+		n.logger.Warn().Err(err).Msgf("Notify: attempt %d of %s to %s failed, retrying in %s", attempt, kind, msg.To, wait)
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// Wait blocks until every notification has been sent or given up on, or ctx
+// is done.
+func (n *Notifier) Wait(ctx context.Context) error {
+	if n == nil {
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,196 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Reskill-2022/volunteering/model"
+)
+
+// flakySender fails the first failures sends.
+type flakySender struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+	sent     []Message
+}
+
+func (f *flakySender) Send(ctx context.Context, msg Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.attempts++
+	if f.attempts <= f.failures {
+		return fmt.Errorf("connection refused")
+	}
+	f.sent = append(f.sent, msg)
+	return nil
+}
+
+func TestNotify(t *testing.T) {
+	testCases := []struct {
+		failures int
+		attempts int
+		sent     int
+	}{
+		{0, 1, 1},
+		{2, 3, 1},
+		{MaxAttempts, MaxAttempts, 0},
+	}
+	for _, tc := range testCases {
+		sender := &flakySender{failures: tc.failures}
+		n := New(zerolog.Nop(), sender, "info@example.org", time.Millisecond)
+
+		n.Notify(ApplicationReceived, model.User{Email: "jane@example.com", FirstName: "Jane"})
+		if err := n.Wait(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if sender.attempts != tc.attempts || len(sender.sent) != tc.sent {
+			t.Errorf("%d failures: expected %d attempts and %d sent, got %d and %d", tc.failures, tc.attempts, tc.sent, sender.attempts, len(sender.sent))
+		}
+	}
+
+	// without a sender nothing happens
+	var n *Notifier
+	n.Notify(AccountCreated, model.User{Email: "jane@example.com"})
+}
+
+func TestRender(t *testing.T) {
+	subject, body, err := render(ReviewDecision, model.User{FirstName: "Jane", Status: model.StatusApproved})
+	if err != nil || subject != "Your volunteer application was approved" || !strings.Contains(body, "Hi Jane,") || !strings.Contains(body, "delighted") {
+		t.Errorf("unexpected message %q %q %v", subject, body, err)
+	}
+
+	subject, body, err = render(ReviewDecision, model.User{Status: model.StatusRejected})
+	if err != nil || subject != "An update on your volunteer application" || !strings.Contains(body, "Hi there,") || strings.Contains(body, "delighted") {
+		t.Errorf("unexpected message %q %q %v", subject, body, err)
+	}
+
+	if _, _, err := render("unknown", model.User{}); err == nil {
+		t.Errorf("expected an error for an unknown notification")
+	}
+}
+
+func TestFileSender(t *testing.T) {
+	dir := t.TempDir()
+	sender := &FileSender{Dir: dir}
+
+	err := sender.Send(context.Background(), Message{From: "info@example.org", To: "jane@example.com", Subject: "Hello", Body: "Hi\nJane\n"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 || !strings.HasSuffix(files[0].Name(), "-jane@example.com.eml") {
+		t.Fatalf("unexpected files %v", files)
+	}
+	raw, _ := os.ReadFile(dir + "/" + files[0].Name())
+	if !strings.Contains(string(raw), "Subject: Hello\r\n") || !strings.HasSuffix(string(raw), "\r\nHi\r\nJane\r\n") {
+		t.Errorf("unexpected message %q", raw)
+	}
+}
+
+// serveSMTP answers one SMTP session on l, without extensions, and returns
+// the message data.
+func serveSMTP(t *testing.T, l net.Listener) <-chan string {
+	data := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		fmt.Fprint(conn, "220 localhost ready\r\n")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.Fields(line)[0]); cmd {
+			case "EHLO", "HELO", "MAIL", "RCPT":
+				fmt.Fprint(conn, "250 OK\r\n")
+			case "DATA":
+				fmt.Fprint(conn, "354 go ahead\r\n")
+				var b strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					b.WriteString(line)
+				}
+				data <- b.String()
+				fmt.Fprint(conn, "250 OK\r\n")
+			case "QUIT":
+				fmt.Fprint(conn, "221 bye\r\n")
+				return
+			default:
+				t.Errorf("unexpected SMTP command %q", line)
+				return
+			}
+		}
+	}()
+	return data
+}
+
+func TestSMTPSender(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer l.Close()
+	data := serveSMTP(t, l)
+
+	sender := &SMTPSender{Addr: l.Addr().String(), Host: "127.0.0.1"}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := sender.Send(ctx, Message{From: "info@example.org", To: "jane@example.com", Subject: "Hello", Body: "Hi"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := <-data; !strings.Contains(got, "Subject: Hello\r\n") {
+		t.Errorf("unexpected message %q", got)
+	}
+}
+
+func TestSMTPSenderTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer l.Close()
+	// accept, then never greet
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(5 * time.Second)
+		}
+	}()
+
+	sender := &SMTPSender{Addr: l.Addr().String(), Host: "127.0.0.1"}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := sender.Send(ctx, Message{From: "info@example.org", To: "jane@example.com"}); err == nil {
+		t.Fatal("expected a stalled server to fail the send")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("send took %s, want it bounded by the context", elapsed)
+	}
+}
@@ -0,0 +1,74 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/Reskill-2022/volunteering/model"
+)
+
+// Notification kinds.
+const (
+	AccountCreated      = "account_created"
+	ApplicationReceived = "application_received"
+	ReviewDecision      = "review_decision"
+)
+
+// templates hold the subject on their first line and the body after a blank
+// line. They are executed with the volunteer.
+var templates = map[string]*template.Template{
+	AccountCreated: parse(AccountCreated, `Welcome to Reskill Americans volunteering
+
+Hi {{.FirstName}},
+
+Thank you for signing up to volunteer with Reskill Americans. Your account is
+ready; complete the application form to tell us how you would like to help.
+
+The Reskill Americans Volunteering Team
+`),
+	ApplicationReceived: parse(ApplicationReceived, `We received your volunteer application
+
+Hi {{.FirstName}},
+
+Thank you for applying to volunteer with Reskill Americans. Our team will
+review your application and let you know the outcome by email.
+
+The Reskill Americans Volunteering Team
+`),
+	ReviewDecision: parse(ReviewDecision, `{{if eq .Status "approved"}}Your volunteer application was approved{{else}}An update on your volunteer application{{end}}
+
+Hi {{.FirstName}},
+{{if eq .Status "approved"}}
+We are delighted to welcome you as a Reskill Americans volunteer. A member of
+our team will be in touch shortly about next steps.
+{{else}}
+Thank you for your interest in volunteering with Reskill Americans.
+Unfortunately we are unable to accept your application at this time.
+{{end}}
+The Reskill Americans Volunteering Team
+`),
+}
+
+func parse(name, text string) *template.Template {
+	return template.Must(template.New(name).Option("missingkey=error").Parse(text))
+}
+
+// render returns the subject and body of the kind of notification for user.
+func render(kind string, user model.User) (string, string, error) {
+	t, ok := templates[kind]
+	if !ok {
+		return "", "", fmt.Errorf("unknown notification '%s'", kind)
+	}
+	if user.FirstName == "" {
+		user.FirstName = "there"
+	}
+
+	var b bytes.Buffer
+	if err := t.Execute(&b, user); err != nil {
+		return "", "", fmt.Errorf("failed to render %s: %w", kind, err)
+	}
+	subject, body, _ := strings.Cut(b.String(), "\n\n")
+	return strings.TrimSpace(subject), body, nil
+}
@@ -0,0 +1,166 @@
+// Package replication keeps a durable write-ahead log of user mutations so
+// that writes to secondary stores can be retried after a failure.
+package replication
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var pendingBucket = []byte("pending")
+
+type Op string
+
+const (
+	OpCreate Op = "create"
+	OpUpdate Op = "update"
+)
+
+// Entry records one mutation that must be applied to every replica.
+type Entry struct {
+	ID          uint64    `json:"id"`
+	Op          Op        `json:"op"`
+	Email       string    `json:"email"`
+	Target      string    `json:"target"`
+	CreatedAt   time.Time `json:"created_at"`
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"last_error"`
+	NextAttempt time.Time `json:"next_attempt"`
+}
+
+// Log is a bolt-backed outbox of mutations that have not yet been applied to
+// their target replica. Entries are removed once they have been applied.
+type Log struct {
+	db *bolt.DB
+}
+
+func Open(path string) (*Log, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open replication log: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(pendingBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialise replication log: %w", err)
+	}
+
+	return &Log{db: db}, nil
+}
+
+func (l *Log) Close() error {
+	return l.db.Close()
+}
+
+// Append durably records e and returns it with its assigned ID.
+func (l *Log) Append(e Entry) (Entry, error) {
+	err := l.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(pendingBucket)
+
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		e.ID = id
+		if e.CreatedAt.IsZero() {
+			e.CreatedAt = time.Now().UTC()
+		}
+		if e.NextAttempt.IsZero() {
+			e.NextAttempt = e.CreatedAt
+		}
+
+		return put(bucket, e)
+	})
+	if err != nil {
+		return Entry{}, fmt.Errorf("failed to append replication entry: %w", err)
+	}
+
+	metrics.Add("appended", 1)
+	return e, nil
+}
+
+// Complete removes an applied entry from the log.
+func (l *Log) Complete(id uint64) error {
+	return l.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(pendingBucket).Delete(key(id))
+	})
+}
+
+// Fail records a failed attempt to apply entry id and schedules the next one.
+func (l *Log) Fail(id uint64, cause error, next time.Time) error {
+	return l.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(pendingBucket)
+
+		raw := bucket.Get(key(id))
+		if raw == nil {
+			return nil
+		}
+
+		var e Entry
+		if err := json.Unmarshal(raw, &e); err != nil {
+			return err
+		}
+		e.Attempts++
+		e.LastError = cause.Error()
+		e.NextAttempt = next
+
+		return put(bucket, e)
+	})
+}
+
+// Pending returns every entry not yet applied, oldest first.
+func (l *Log) Pending() ([]Entry, error) {
+	var entries []Entry
+	err := l.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(pendingBucket).ForEach(func(_, v []byte) error {
+			var e Entry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			entries = append(entries, e)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replication log: %w", err)
+	}
+	return entries, nil
+}
+
+func put(bucket *bolt.Bucket, e Entry) error {
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(key(e.ID), raw)
+}
+
+// key encodes id big-endian so that bolt iterates entries in append order.
+func key(id uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, id)
+	return b
+}
+
+// Backoff returns the delay before retry number attempts, doubling from base
+// up to a maximum of one hour.
+func Backoff(base time.Duration, attempts int) time.Duration {
+	const max = time.Hour
+
+	delay := base
+	for i := 0; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
@@ -0,0 +1,71 @@
+package replication
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "replication.db")
+	log, err := Open(path)
+	if err != nil {
+		t.Fatalf("failed to open log: %v", err)
+	}
+
+	first, err := log.Append(Entry{Op: OpCreate, Email: "a@example.com", Target: "client2"})
+	if err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	second, err := log.Append(Entry{Op: OpUpdate, Email: "b@example.com", Target: "client2"})
+	if err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+
+	next := time.Now().Add(time.Minute).UTC()
+	if err := log.Fail(second.ID, fmt.Errorf("unavailable"), next); err != nil {
+		t.Fatalf("failed to record failure: %v", err)
+	}
+	if err := log.Complete(first.ID); err != nil {
+		t.Fatalf("failed to complete: %v", err)
+	}
+
+	// entries must survive a restart
+	log.Close()
+	log, err = Open(path)
+	if err != nil {
+		t.Fatalf("failed to reopen log: %v", err)
+	}
+	defer log.Close()
+
+	pending, err := log.Pending()
+	if err != nil {
+		t.Fatalf("failed to read pending: %v", err)
+	}
+	if len(pending) != 1 {
+		t.Fatalf("expected 1 pending entry, got %d", len(pending))
+	}
+	got := pending[0]
+	if got.Email != "b@example.com" || got.Attempts != 1 || got.LastError != "unavailable" || !got.NextAttempt.Equal(next) {
+		t.Errorf("unexpected pending entry %+v", got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	testCases := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, time.Minute},
+		{3, 4 * time.Minute},
+		{20, time.Hour},
+	}
+
+	for _, tc := range testCases {
+		if got := Backoff(30*time.Second, tc.attempts); got != tc.want {
+			t.Errorf("Backoff(30s, %d) = %s, want %s", tc.attempts, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package replication
+
+import (
+	"expvar"
+	"time"
+)
+
+// metrics is published through expvar under "replication".
+var metrics = expvar.NewMap("replication")
+
+// RecordBacklog publishes the number of pending entries and the age of the
+// oldest one, which is the current replication lag.
+func RecordBacklog(pending []Entry, now time.Time) {
+	count := new(expvar.Int)
+	count.Set(int64(len(pending)))
+	metrics.Set("pending", count)
+
+	lag := new(expvar.Float)
+	if len(pending) > 0 {
+		lag.Set(now.Sub(pending[0].CreatedAt).Seconds())
+	}
+	metrics.Set("lag_seconds", lag)
+}
+
+// RecordRetry counts a retried entry and whether the retry succeeded.
+func RecordRetry(err error) {
+	metrics.Add("retries", 1)
+	if err != nil {
+		metrics.Add("retry_failures", 1)
+	}
+}
+
+// RecordReconcile publishes the outcome of a reconciliation run.
+func RecordReconcile(r Report) {
+	metrics.Add("reconcile_runs", 1)
+	metrics.Add("reconcile_repaired", int64(r.Repaired))
+	metrics.Add("reconcile_conflicts", int64(r.Conflicts))
+	metrics.Add("reconcile_orphans", int64(r.Orphans))
+
+	last := new(expvar.String)
+	last.Set(r.FinishedAt.Format(time.RFC3339))
+	metrics.Set("reconcile_last_run", last)
+}
+
+// Report summarises a reconciliation run between the primary and Target.
+type Report struct {
+	Target     string    `json:"target"`
+	Checked    int       `json:"checked"`
+	Repaired   int       `json:"repaired"`
+	Conflicts  int       `json:"conflicts"`
+	Orphans    int       `json:"orphans"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
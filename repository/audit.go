@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/Reskill-2022/volunteering/audit"
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/model"
+)
+
+const auditCollection = "audit"
+
+// AuditStore is the append-only log of changes to volunteers. There is no
+// way to edit or remove an entry.
+type AuditStore interface {
+	AppendAudit(ctx context.Context, entry model.AuditEntry) error
+	// ListAudit returns the entries of the volunteer with email, oldest
+	// first.
+	ListAudit(ctx context.Context, email string) ([]model.AuditEntry, error)
+}
+
+var (
+	_ AuditStore = (*UserRepository)(nil)
+	_ AuditStore = (*BoltUserRepository)(nil)
+	_ AuditStore = (*MemoryUserRepository)(nil)
+
+	_ UserRepositoryInterface = (*AuditedUserRepository)(nil)
+)
+
+// AuditedUserRepository records every user it creates or updates in an
+// AuditStore, with the actor and endpoint carried by the context (see
+// package audit).
+type AuditedUserRepository struct {
+	UserRepositoryInterface
+	logger zerolog.Logger
+	store  AuditStore
+}
+
+func NewAuditedUserRepository(logger zerolog.Logger, users UserRepositoryInterface, store AuditStore) *AuditedUserRepository {
+	return &AuditedUserRepository{
+		UserRepositoryInterface: users,
+		logger:                  logger,
+		store:                   store,
+	}
+}
+
+// Unwrap returns the repository that a is recording changes to.
+func (a *AuditedUserRepository) Unwrap() UserRepositoryInterface {
+	return a.UserRepositoryInterface
+}
+
+// CreateUser only records users that did not exist yet. Signing in again
+// returns the existing user unchanged.
+func (a *AuditedUserRepository) CreateUser(ctx context.Context, user model.User) (*model.User, bool, error) {
+	stored, created, err := a.UserRepositoryInterface.CreateUser(ctx, user)
+	if err != nil || !created {
+		return stored, created, err
+	}
+
+	a.record(ctx, model.AuditCreate, model.User{}, *stored)
+	return stored, true, nil
+}
+
+// UpdateUser diffs user against the stored record read just before the
+// write. The read and the write are not one transaction: when two updates of
+// the same volunteer race, an entry may be diffed against a record the other
+// update has already replaced, so it can repeat or miss that update's
+// changes. The stored user is always right; only the entry is affected.
+func (a *AuditedUserRepository) UpdateUser(ctx context.Context, user model.User) (*model.User, error) {
+	before, err := a.UserRepositoryInterface.GetUser(ctx, user.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := a.UserRepositoryInterface.UpdateUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	a.record(ctx, model.AuditUpdate, *before, *updated)
+	return updated, nil
+}
+
+// record appends the changes from before to after, if any. The change is
+// already stored, so failing to record it is logged rather than returned.
+func (a *AuditedUserRepository) record(ctx context.Context, action string, before, after model.User) {
+	changes, err := audit.Diff(before, after)
+	if err != nil {
+		a.logger.Err(err).Msgf("Audit: failed to diff %s", after.Email)
+		return
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	actor := audit.ActorFrom(ctx)
+	entry := model.AuditEntry{
+		Email:    after.Email,
+		Action:   action,
+		Actor:    actor.Name,
+		Role:     actor.Role,
+		Endpoint: audit.EndpointFrom(ctx),
+		Changes:  changes,
+		At:       time.Now().UTC(),
+	}
+	if err := a.store.AppendAudit(ctx, entry); err != nil {
+		a.logger.Err(err).Msgf("Audit: failed to record %s of %s by %s (%s)", action, after.Email, actor.Name, actor.Role)
+	}
+}
+
+// AppendAudit writes to the primary only. The audit log is not replicated.
+func (u *UserRepository) AppendAudit(ctx context.Context, entry model.AuditEntry) error {
+	_, err := u.primary.client.Collection(auditCollection).NewDoc().Create(ctx, entry)
+	if err != nil {
+		return errors.From(err, fmt.Sprintf("%s failed to append audit entry", u.primary.name), 500)
+	}
+	return nil
+}
+
+// ListAudit sorts in memory so that no composite index is needed.
+func (u *UserRepository) ListAudit(ctx context.Context, email string) ([]model.AuditEntry, error) {
+	docs, err := u.primary.client.Collection(auditCollection).Where("email", "==", email).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, errors.From(err, fmt.Sprintf("%s failed to list audit entries", u.primary.name), 500)
+	}
+
+	entries := make([]model.AuditEntry, 0, len(docs))
+	for _, doc := range docs {
+		var entry model.AuditEntry
+		if err := doc.DataTo(&entry); err != nil {
+			return nil, errors.From(err, "failed to bind audit entry", 500)
+		}
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].At.Before(entries[j].At) })
+	return entries, nil
+}
+
+// auditKey orders a volunteer's entries by sequence after their email.
+func auditKey(email string, seq uint64) []byte {
+	key := make([]byte, len(email)+1+8)
+	copy(key, email)
+	binary.BigEndian.PutUint64(key[len(email)+1:], seq)
+	return key
+}
+
+func (b *BoltUserRepository) AppendAudit(ctx context.Context, entry model.AuditEntry) error {
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return errors.From(err, "failed to encode audit entry", 500)
+	}
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(auditCollection))
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		return bucket.Put(auditKey(entry.Email, seq), raw)
+	})
+	if err != nil {
+		return errors.From(err, "failed to append audit entry", 500)
+	}
+	return nil
+}
+
+func (b *BoltUserRepository) ListAudit(ctx context.Context, email string) ([]model.AuditEntry, error) {
+	prefix := append([]byte(email), 0)
+
+	entries := []model.AuditEntry{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(auditCollection)).Cursor()
+		for k, v := c.Seek(prefix); k != nil && len(k) == len(prefix)+8 && string(k[:len(prefix)]) == string(prefix); k, v = c.Next() {
+			var entry model.AuditEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.From(err, "failed to list audit entries", 500)
+	}
+	return entries, nil
+}
+
+type memoryAudit struct {
+	mu      sync.RWMutex
+	entries map[string][]model.AuditEntry
+}
+
+func (m *MemoryUserRepository) AppendAudit(ctx context.Context, entry model.AuditEntry) error {
+	m.audit.mu.Lock()
+	defer m.audit.mu.Unlock()
+
+	if m.audit.entries == nil {
+		m.audit.entries = make(map[string][]model.AuditEntry)
+	}
+	m.audit.entries[entry.Email] = append(m.audit.entries[entry.Email], entry)
+	return nil
+}
+
+func (m *MemoryUserRepository) ListAudit(ctx context.Context, email string) ([]model.AuditEntry, error) {
+	m.audit.mu.RLock()
+	defer m.audit.mu.RUnlock()
+
+	return append([]model.AuditEntry{}, m.audit.entries[email]...), nil
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/rs/zerolog"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/model"
+)
+
+// BoltUserRepository is an embedded, single-file implementation of
+// UserRepositoryInterface. It needs no external services, which makes it
+// suitable for local development and tests.
+type BoltUserRepository struct {
+	logger zerolog.Logger
+	db     *bolt.DB
+}
+
+var _ UserRepositoryInterface = (*BoltUserRepository)(nil)
+
+func NewBoltUserRepository(logger zerolog.Logger, db *bolt.DB) (*BoltUserRepository, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range []string{collectionName, taxonomyCollection, formsCollection, auditCollection, deliveriesCollection} {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &BoltUserRepository{
+		logger: logger,
+		db:     db,
+	}, nil
+}
+
+func (b *BoltUserRepository) CreateUser(ctx context.Context, user model.User) (*model.User, bool, error) {
+	b.logger.Debug().Msgf("Bolt: creating user with email: %s", user.Email)
+
+	var existing *model.User
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(collectionName))
+
+		if raw := bucket.Get([]byte(user.Email)); raw != nil {
+			existing = &model.User{}
+			return json.Unmarshal(raw, existing)
+		}
+
+		raw, err := json.Marshal(user)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(user.Email), raw)
+	})
+	if err != nil {
+		return nil, false, errors.From(err, "failed to create user", 500)
+	}
+
+	if existing != nil {
+		return existing, false, nil
+	}
+	return &user, true, nil
+}
+
+func (b *BoltUserRepository) UpdateUser(ctx context.Context, user model.User) (*model.User, error) {
+	b.logger.Debug().Msgf("Bolt: updating user with email: %s", user.Email)
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(collectionName))
+
+		if bucket.Get([]byte(user.Email)) == nil {
+			return errors.New("User Account Not Found", 404)
+		}
+
+		raw, err := json.Marshal(user)
+		if err != nil {
+			return errors.From(err, "failed to encode user data", 500)
+		}
+		if err := bucket.Put([]byte(user.Email), raw); err != nil {
+			return errors.From(err, "failed to update user data", 500)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (b *BoltUserRepository) GetUser(ctx context.Context, email string) (*model.User, error) {
+	b.logger.Debug().Msgf("Bolt: getting user with email: %s", email)
+
+	var raw []byte
+	err := b.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket([]byte(collectionName)).Get([]byte(email)); v != nil {
+			raw = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.From(err, "failed to read user data", 500)
+	}
+	if raw == nil {
+		return nil, errors.New("User Account Not Found", 404)
+	}
+
+	user := model.User{}
+	if err := json.Unmarshal(raw, &user); err != nil {
+		return nil, errors.From(err, "failed to bind user data", 500)
+	}
+
+	return &user, nil
+}
+
+func (b *BoltUserRepository) ListUsers(ctx context.Context, query UserQuery) (*UserPage, error) {
+	b.logger.Debug().Msgf("Bolt: listing users after: %s", query.Cursor)
+
+	var users []model.User
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(collectionName)).ForEach(func(k, v []byte) error {
+			var user model.User
+			if err := json.Unmarshal(v, &user); err != nil {
+				return err
+			}
+			users = append(users, user)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errors.From(err, "failed to list users", 500)
+	}
+
+	return query.run(users)
+}
@@ -1,13 +1,154 @@
 package repository
 
-import "github.com/rs/zerolog"
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/Reskill-2022/volunteering/config"
+	"github.com/Reskill-2022/volunteering/jobs"
+	"github.com/Reskill-2022/volunteering/replication"
+)
 
 type Container struct {
-	UserRepository *UserRepository
+	UserRepository UserRepositoryInterface
+	Taxonomy       TaxonomyStore
+	Forms          FormStore
+	Audit          AuditStore
+	Deliveries     DeliveryStore
+	Jobs           *jobs.Queue
+
+	background []func(ctx context.Context)
+}
+
+// NewContainer builds the repositories for the storage driver selected in cfg.
+func NewContainer(logger zerolog.Logger, cfg *config.Config) (*Container, error) {
+	queue, err := jobs.Open(logger, cfg.Jobs.Path, jobs.Options{
+		Workers:       cfg.Jobs.Workers,
+		RetryInterval: cfg.Jobs.RetryInterval,
+		MaxAttempts:   cfg.Jobs.MaxAttempts,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := newContainer(logger, cfg, queue)
+	if err != nil {
+		queue.Close()
+		return nil, err
+	}
+	rc.Jobs = queue
+	rc.background = append(rc.background, queue.Run)
+	return rc, nil
+}
+
+// NewCommandContainer builds the repositories for commands, which may run
+// next to the server. It opens neither the job queue nor the replication
+// log, whose files the server holds locked, so Jobs is nil, Start runs
+// nothing and failed Firestore mirror writes are left to reconciliation.
+func NewCommandContainer(logger zerolog.Logger, cfg *config.Config) (*Container, error) {
+	return newContainer(logger, cfg, nil)
+}
+
+// newContainer builds the repositories, with the replication log and
+// deferred mirror writes when queue is set.
+func newContainer(logger zerolog.Logger, cfg *config.Config, queue *jobs.Queue) (*Container, error) {
+	switch driver := cfg.Storage.Driver; driver {
+	case config.DriverFirestore, "":
+		if queue == nil {
+			users, err := NewUserRepository(logger, nil, FirestoreOptions(cfg.Firestore))
+			if err != nil {
+				return nil, fmt.Errorf("failed to initialise firestore user repository: %w", err)
+			}
+			return firestoreContainer(logger, users), nil
+		}
+
+		log, err := replication.Open(cfg.Replication.LogPath)
+		if err != nil {
+			return nil, err
+		}
+
+		users, err := NewUserRepository(logger, log, FirestoreOptions(cfg.Firestore))
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialise firestore user repository: %w", err)
+		}
+		users.DeferMirrorWrites(queue)
+		retryInterval := cfg.Replication.RetryInterval
+		reconcileInterval := cfg.Replication.ReconcileInterval
+
+		rc := firestoreContainer(logger, users)
+		rc.background = []func(ctx context.Context){
+			func(ctx context.Context) { users.RunReplication(ctx, retryInterval, reconcileInterval) },
+		}
+		return rc, nil
+
+	case config.DriverBolt:
+		db, err := bolt.Open(cfg.Storage.Path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+		if err != nil {
+			return nil, fmt.Errorf("failed to open bolt database: %w", err)
+		}
+
+		users, err := NewBoltUserRepository(logger, db)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialise bolt user repository: %w", err)
+		}
+
+		return &Container{
+			UserRepository: NewAuditedUserRepository(logger, users, users),
+			Taxonomy:       users,
+			Forms:          users,
+			Audit:          users,
+			Deliveries:     users,
+		}, nil
+
+	case config.DriverMemory:
+		users := NewMemoryUserRepository(logger)
+		return &Container{
+			UserRepository: NewAuditedUserRepository(logger, users, users),
+			Taxonomy:       users,
+			Forms:          users,
+			Audit:          users,
+			Deliveries:     users,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown storage driver '%s'", driver)
+	}
 }
 
-func NewContainer(logger zerolog.Logger) *Container {
+func firestoreContainer(logger zerolog.Logger, users *UserRepository) *Container {
 	return &Container{
-		UserRepository: NewUserRepository(logger),
+		UserRepository: NewAuditedUserRepository(logger, users, users),
+		Taxonomy:       users,
+		Forms:          users,
+		Audit:          users,
+		Deliveries:     users,
+	}
+}
+
+// Start runs the background work of the selected driver, such as replication
+// retries, and the job queue until ctx is done.
+func (c *Container) Start(ctx context.Context) {
+	for _, run := range c.background {
+		go run(ctx)
+	}
+}
+
+// FirestoreOptions maps the configured replicas to their service accounts.
+func FirestoreOptions(cfg config.Firestore) Options {
+	opts := Options{
+		WritePolicy: cfg.WritePolicy,
+		ReadPolicy:  cfg.ReadPolicy,
+	}
+	for _, r := range cfg.Replicas {
+		opts.Replicas = append(opts.Replicas, ReplicaOptions{
+			Name:            r.Name,
+			Role:            r.Role,
+			CredentialsJSON: []byte(r.Credentials),
+		})
 	}
+	return opts
 }
@@ -8,7 +8,10 @@ import (
 
 type (
 	UserCreator interface {
-		CreateUser(ctx context.Context, user model.User) (*model.User, error)
+		// CreateUser stores user unless one with the same email exists, in
+		// which case the existing user is returned unchanged. created
+		// reports which happened.
+		CreateUser(ctx context.Context, user model.User) (stored *model.User, created bool, err error)
 	}
 
 	UserUpdater interface {
@@ -19,9 +22,15 @@ type (
 		GetUser(ctx context.Context, email string) (*model.User, error)
 	}
 
+	// UserLister pages through the users matching a UserQuery.
+	UserLister interface {
+		ListUsers(ctx context.Context, query UserQuery) (*UserPage, error)
+	}
+
 	UserRepositoryInterface interface {
 		UserCreator
 		UserUpdater
 		UserGetter
+		UserLister
 	}
 )
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"cloud.google.com/go/firestore"
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/model"
+)
+
+const formsCollection = "forms"
+
+var (
+	errFormNotFound = errors.New("Form Version Not Found", 404)
+	errFormExists   = errors.New("Form Version Already Exists. Please Retry", 409)
+)
+
+// FormStore keeps every version of the application form.
+type FormStore interface {
+	GetForm(ctx context.Context, version int) (*model.Form, error)
+	// LatestForm returns the highest version, or a 404 error if no form
+	// was ever saved.
+	LatestForm(ctx context.Context) (*model.Form, error)
+	// CreateForm saves form under form.Version, failing with a 409 error if
+	// that version exists.
+	CreateForm(ctx context.Context, form model.Form) error
+}
+
+var (
+	_ FormStore = (*UserRepository)(nil)
+	_ FormStore = (*BoltUserRepository)(nil)
+	_ FormStore = (*MemoryUserRepository)(nil)
+)
+
+// Forms are kept in the primary only, like the option lists.
+func (u *UserRepository) GetForm(ctx context.Context, version int) (*model.Form, error) {
+	doc, err := u.primary.client.Collection(formsCollection).Doc(strconv.Itoa(version)).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, errFormNotFound
+	}
+	if err != nil {
+		return nil, errors.From(err, fmt.Sprintf("%s failed to get form", u.primary.name), 500)
+	}
+
+	var form model.Form
+	if err := doc.DataTo(&form); err != nil {
+		return nil, errors.From(err, "failed to bind form", 500)
+	}
+	return &form, nil
+}
+
+func (u *UserRepository) LatestForm(ctx context.Context) (*model.Form, error) {
+	docs, err := u.primary.client.Collection(formsCollection).OrderBy("version", firestore.Desc).Limit(1).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, errors.From(err, fmt.Sprintf("%s failed to get latest form", u.primary.name), 500)
+	}
+	if len(docs) == 0 {
+		return nil, errFormNotFound
+	}
+
+	var form model.Form
+	if err := docs[0].DataTo(&form); err != nil {
+		return nil, errors.From(err, "failed to bind form", 500)
+	}
+	return &form, nil
+}
+
+func (u *UserRepository) CreateForm(ctx context.Context, form model.Form) error {
+	u.logger.Debug().Msgf("Firestore: creating form version: %d", form.Version)
+
+	_, err := u.primary.client.Collection(formsCollection).Doc(strconv.Itoa(form.Version)).Create(ctx, form)
+	if status.Code(err) == codes.AlreadyExists {
+		return errFormExists
+	}
+	if err != nil {
+		return errors.From(err, fmt.Sprintf("%s failed to create form", u.primary.name), 500)
+	}
+	return nil
+}
+
+// formKey orders versions numerically in the bucket.
+func formKey(version int) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(version))
+	return key
+}
+
+func (b *BoltUserRepository) GetForm(ctx context.Context, version int) (*model.Form, error) {
+	var raw []byte
+	err := b.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket([]byte(formsCollection)).Get(formKey(version)); v != nil {
+			raw = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.From(err, "failed to read form", 500)
+	}
+	if raw == nil {
+		return nil, errFormNotFound
+	}
+	return decodeForm(raw)
+}
+
+func (b *BoltUserRepository) LatestForm(ctx context.Context) (*model.Form, error) {
+	var raw []byte
+	err := b.db.View(func(tx *bolt.Tx) error {
+		if _, v := tx.Bucket([]byte(formsCollection)).Cursor().Last(); v != nil {
+			raw = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.From(err, "failed to read form", 500)
+	}
+	if raw == nil {
+		return nil, errFormNotFound
+	}
+	return decodeForm(raw)
+}
+
+func (b *BoltUserRepository) CreateForm(ctx context.Context, form model.Form) error {
+	b.logger.Debug().Msgf("Bolt: creating form version: %d", form.Version)
+
+	raw, err := json.Marshal(form)
+	if err != nil {
+		return errors.From(err, "failed to encode form", 500)
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(formsCollection))
+		if bucket.Get(formKey(form.Version)) != nil {
+			return errFormExists
+		}
+		if err := bucket.Put(formKey(form.Version), raw); err != nil {
+			return errors.From(err, "failed to create form", 500)
+		}
+		return nil
+	})
+}
+
+func decodeForm(raw []byte) (*model.Form, error) {
+	var form model.Form
+	if err := json.Unmarshal(raw, &form); err != nil {
+		return nil, errors.From(err, "failed to bind form", 500)
+	}
+	return &form, nil
+}
+
+func (m *MemoryUserRepository) GetForm(ctx context.Context, version int) (*model.Form, error) {
+	m.forms.mu.RLock()
+	defer m.forms.mu.RUnlock()
+
+	for _, form := range m.forms.versions {
+		if form.Version == version {
+			return &form, nil
+		}
+	}
+	return nil, errFormNotFound
+}
+
+func (m *MemoryUserRepository) LatestForm(ctx context.Context) (*model.Form, error) {
+	m.forms.mu.RLock()
+	defer m.forms.mu.RUnlock()
+
+	var latest *model.Form
+	for i, form := range m.forms.versions {
+		if latest == nil || form.Version > latest.Version {
+			latest = &m.forms.versions[i]
+		}
+	}
+	if latest == nil {
+		return nil, errFormNotFound
+	}
+	form := *latest
+	return &form, nil
+}
+
+func (m *MemoryUserRepository) CreateForm(ctx context.Context, form model.Form) error {
+	m.logger.Debug().Msgf("Memory: creating form version: %d", form.Version)
+
+	m.forms.mu.Lock()
+	defer m.forms.mu.Unlock()
+
+	for _, f := range m.forms.versions {
+		if f.Version == form.Version {
+			return errFormExists
+		}
+	}
+	m.forms.versions = append(m.forms.versions, form)
+	return nil
+}
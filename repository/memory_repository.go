@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/model"
+)
+
+// MemoryUserRepository is a thread-safe, in-process implementation of
+// UserRepositoryInterface. Data is lost when the process exits.
+type MemoryUserRepository struct {
+	logger zerolog.Logger
+
+	mu    sync.RWMutex
+	users map[string]model.User
+
+	taxonomy memoryTaxonomy
+	forms    memoryForms
+	audit    memoryAudit
+
+	deliveries memoryDeliveries
+}
+
+var _ UserRepositoryInterface = (*MemoryUserRepository)(nil)
+
+func NewMemoryUserRepository(logger zerolog.Logger) *MemoryUserRepository {
+	return &MemoryUserRepository{
+		logger: logger,
+		users:  make(map[string]model.User),
+	}
+}
+
+func (m *MemoryUserRepository) CreateUser(ctx context.Context, user model.User) (*model.User, bool, error) {
+	m.logger.Debug().Msgf("Memory: creating user with email: %s", user.Email)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, ok := m.users[user.Email]; ok {
+		existing = clone(existing)
+		return &existing, false, nil
+	}
+	m.users[user.Email] = clone(user)
+
+	return &user, true, nil
+}
+
+func (m *MemoryUserRepository) UpdateUser(ctx context.Context, user model.User) (*model.User, error) {
+	m.logger.Debug().Msgf("Memory: updating user with email: %s", user.Email)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[user.Email]; !ok {
+		return nil, errors.New("User Account Not Found", 404)
+	}
+	m.users[user.Email] = clone(user)
+
+	return &user, nil
+}
+
+func (m *MemoryUserRepository) GetUser(ctx context.Context, email string) (*model.User, error) {
+	m.logger.Debug().Msgf("Memory: getting user with email: %s", email)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.users[email]
+	if !ok {
+		return nil, errors.New("User Account Not Found", 404)
+	}
+	user = clone(user)
+
+	return &user, nil
+}
+
+func (m *MemoryUserRepository) ListUsers(ctx context.Context, query UserQuery) (*UserPage, error) {
+	m.logger.Debug().Msgf("Memory: listing users after: %s", query.Cursor)
+
+	m.mu.RLock()
+	users := make([]model.User, 0, len(m.users))
+	for _, user := range m.users {
+		users = append(users, clone(user))
+	}
+	m.mu.RUnlock()
+
+	return query.run(users)
+}
+
+// clone copies user deeply enough that neither the caller nor the store can
+// change the other's copy.
+func clone(user model.User) model.User {
+	user.Positions = cloneStrings(user.Positions)
+	user.VolunteerAreas = cloneStrings(user.VolunteerAreas)
+	user.VolunteerMeans = cloneStrings(user.VolunteerMeans)
+	user.Industries = cloneStrings(user.Industries)
+	user.Answers = cloneAnswers(user.Answers)
+	if user.Draft != nil {
+		draft := *user.Draft
+		draft.Answers = cloneAnswers(draft.Answers)
+		if draft.Steps != nil {
+			steps := make(map[string]bool, len(draft.Steps))
+			for step, done := range draft.Steps {
+				steps[step] = done
+			}
+			draft.Steps = steps
+		}
+		user.Draft = &draft
+	}
+	if user.SubmittedAt != nil {
+		at := *user.SubmittedAt
+		user.SubmittedAt = &at
+	}
+	if user.Reviews != nil {
+		user.Reviews = append([]model.Review{}, user.Reviews...)
+	}
+	return user
+}
+
+func cloneStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	return append([]string{}, values...)
+}
+
+// cloneAnswers copies answers and their lists of choices.
+func cloneAnswers(answers map[string]interface{}) map[string]interface{} {
+	if answers == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(answers))
+	for name, value := range answers {
+		switch v := value.(type) {
+		case []string:
+			value = cloneStrings(v)
+		case []interface{}:
+			value = append([]interface{}{}, v...)
+		}
+		copied[name] = value
+	}
+	return copied
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/api/iterator"
+
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/model"
+)
+
+// ListMigrator converts the volunteer areas and means of users stored before
+// they were arrays, which were comma-separated strings.
+type ListMigrator interface {
+	// MigrateVolunteerLists returns the number of users converted, or that
+	// would be with dryRun.
+	MigrateVolunteerLists(ctx context.Context, dryRun bool) (int, error)
+}
+
+var (
+	_ ListMigrator = (*UserRepository)(nil)
+	_ ListMigrator = (*BoltUserRepository)(nil)
+)
+
+// legacyUser is a Firestore user document written before the volunteer
+// lists were arrays.
+type legacyUser struct {
+	model.User
+	VolunteerAreas string `firestore:"volunteer_areas"`
+	VolunteerMeans string `firestore:"volunteer_means"`
+}
+
+// decodeUser reads a user document, whether or not it has been migrated.
+func decodeUser(doc *firestore.DocumentSnapshot) (*model.User, error) {
+	var user model.User
+	if err := doc.DataTo(&user); err == nil {
+		return &user, nil
+	}
+
+	var legacy legacyUser
+	if err := doc.DataTo(&legacy); err != nil {
+		return nil, errors.From(err, "failed to bind user data", 500)
+	}
+	user = legacy.User
+	user.VolunteerAreas = model.ParseStringList(legacy.VolunteerAreas)
+	user.VolunteerMeans = model.ParseStringList(legacy.VolunteerMeans)
+	return &user, nil
+}
+
+// MigrateVolunteerLists converts every replica, including read-only ones,
+// in place rather than through the replication log.
+func (u *UserRepository) MigrateVolunteerLists(ctx context.Context, dryRun bool) (int, error) {
+	total := 0
+	for _, r := range u.replicas {
+		n, err := u.migrateReplica(ctx, r, dryRun)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("migrate %s: %w", r.name, err)
+		}
+		u.logger.Info().Msgf("Migration: %d users with legacy volunteer lists in %s (dry run: %t)", n, r.name, dryRun)
+	}
+	return total, nil
+}
+
+func (u *UserRepository) migrateReplica(ctx context.Context, r *replica, dryRun bool) (int, error) {
+	iter := r.client.Collection(collectionName).Documents(ctx)
+	defer iter.Stop()
+
+	n := 0
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+
+		var updates []firestore.Update
+		for _, path := range []string{"volunteer_areas", "volunteer_means"} {
+			if legacy, ok := doc.Data()[path].(string); ok {
+				updates = append(updates, firestore.Update{Path: path, Value: []string(model.ParseStringList(legacy))})
+			}
+		}
+		if len(updates) == 0 {
+			continue
+		}
+
+		n++
+		if dryRun {
+			continue
+		}
+		// fail rather than overwrite if the user changed since it was read
+		if _, err := doc.Ref.Update(ctx, updates, firestore.LastUpdateTime(doc.UpdateTime)); err != nil {
+			return n - 1, err
+		}
+	}
+}
+
+func (b *BoltUserRepository) MigrateVolunteerLists(ctx context.Context, dryRun bool) (int, error) {
+	n := 0
+	migrate := func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(collectionName))
+
+		legacy := make(map[string][]byte)
+		err := bucket.ForEach(func(k, v []byte) error {
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal(v, &fields); err != nil {
+				return err
+			}
+			for _, key := range []string{"volunteer_areas", "volunteer_means"} {
+				if raw := fields[key]; len(raw) > 0 && raw[0] == '"' {
+					legacy[string(k)] = v
+					break
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		n = len(legacy)
+		if dryRun {
+			return nil
+		}
+		for email, v := range legacy {
+			// StringList decodes the legacy strings
+			var user model.User
+			if err := json.Unmarshal(v, &user); err != nil {
+				return err
+			}
+			raw, err := json.Marshal(user)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(email), raw); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var err error
+	if dryRun {
+		err = b.db.View(migrate)
+	} else {
+		err = b.db.Update(migrate)
+	}
+	if err != nil {
+		return 0, errors.From(err, "failed to migrate volunteer lists", 500)
+	}
+	return n, nil
+}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/model"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+// Fields users can be sorted by.
+const (
+	SortEmail        = "email"
+	SortCreatedAt    = "created_at"
+	SortName         = "name"
+	SortState        = "state"
+	SortOrganization = "organization"
+)
+
+type (
+	UserQuery struct {
+		Filter UserFilter
+		// Sort is one of the Sort constants, SortEmail if empty. Ties are
+		// broken by email.
+		Sort string
+		Desc bool
+		// Limit is the page size, DefaultPageSize if zero and at most
+		// MaxPageSize.
+		Limit int
+		// Cursor is the NextCursor of the previous page, empty for the first.
+		Cursor string
+	}
+
+	// UserFilter selects users. Zero fields match every user.
+	UserFilter struct {
+		State        string
+		Organization string
+		Enrolled     *bool
+		// Status matches the stored application status, see model.User.
+		Status string
+		// VolunteerArea matches users who listed exactly it among their
+		// areas, as Firestore's array-contains does.
+		VolunteerArea string
+		// CreatedAfter and CreatedBefore bound CreatedAt, inclusive and
+		// exclusive respectively.
+		CreatedAfter  time.Time
+		CreatedBefore time.Time
+	}
+
+	UserPage struct {
+		Users []model.User `json:"users"`
+		// NextCursor is empty on the last page.
+		NextCursor string `json:"next_cursor,omitempty"`
+	}
+)
+
+// Validate checks that q can be run by every backend. Firestore can only
+// filter CreatedAt by range when it also sorts by it.
+func (q UserQuery) Validate() error {
+	switch q.Sort {
+	case "", SortEmail, SortCreatedAt, SortName, SortState, SortOrganization:
+	default:
+		return errors.New(fmt.Sprintf("Cannot sort by '%s'", q.Sort), 400)
+	}
+
+	f := q.Filter
+	if (!f.CreatedAfter.IsZero() || !f.CreatedBefore.IsZero()) && q.Sort != SortCreatedAt {
+		return errors.New("Filtering by creation date requires sorting by created_at", 400)
+	}
+	if !f.CreatedAfter.IsZero() && !f.CreatedBefore.IsZero() && !f.CreatedAfter.Before(f.CreatedBefore) {
+		return errors.New("created_after must be before created_before", 400)
+	}
+	return nil
+}
+
+func (q UserQuery) limit() int {
+	switch {
+	case q.Limit <= 0:
+		return DefaultPageSize
+	case q.Limit > MaxPageSize:
+		return MaxPageSize
+	default:
+		return q.Limit
+	}
+}
+
+// Matches reports whether user passes every filter in f.
+func (f UserFilter) Matches(user model.User) bool {
+	switch {
+	case f.State != "" && user.State != f.State:
+		return false
+	case f.Organization != "" && user.Organization != f.Organization:
+		return false
+	case f.Enrolled != nil && user.Enrolled != *f.Enrolled:
+		return false
+	case f.Status != "" && user.Status != f.Status:
+		return false
+	case f.VolunteerArea != "" && !user.VolunteerAreas.Contains(f.VolunteerArea):
+		return false
+	case !f.CreatedAfter.IsZero() && user.CreatedAt.Before(f.CreatedAfter):
+		return false
+	case !f.CreatedBefore.IsZero() && !user.CreatedAt.Before(f.CreatedBefore):
+		return false
+	}
+	return true
+}
+
+// less orders a before b by the sort field, then by email.
+func (q UserQuery) less(a, b model.User) bool {
+	var c int
+	switch q.Sort {
+	case SortCreatedAt:
+		switch {
+		case a.CreatedAt.Before(b.CreatedAt):
+			c = -1
+		case a.CreatedAt.After(b.CreatedAt):
+			c = 1
+		}
+	case SortName:
+		c = strings.Compare(a.Name, b.Name)
+	case SortState:
+		c = strings.Compare(a.State, b.State)
+	case SortOrganization:
+		c = strings.Compare(a.Organization, b.Organization)
+	}
+	if c == 0 {
+		c = strings.Compare(a.Email, b.Email)
+	}
+	if q.Desc {
+		return c > 0
+	}
+	return c < 0
+}
+
+// run pages through users in memory, for backends without a query engine.
+func (q UserQuery) run(users []model.User) (*UserPage, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	var after *model.User
+	matched := make([]model.User, 0, len(users))
+	for i, user := range users {
+		if user.Email == q.Cursor {
+			after = &users[i]
+		}
+		if q.Filter.Matches(user) {
+			matched = append(matched, user)
+		}
+	}
+	if q.Cursor != "" && after == nil {
+		return nil, errors.New("Invalid Cursor", 400)
+	}
+
+	sort.Slice(matched, func(i, j int) bool { return q.less(matched[i], matched[j]) })
+
+	if after != nil {
+		start := sort.Search(len(matched), func(i int) bool { return q.less(*after, matched[i]) })
+		matched = matched[start:]
+	}
+
+	limit := q.limit()
+	if len(matched) > limit+1 {
+		matched = matched[:limit+1]
+	}
+	return newPage(matched, limit), nil
+}
+
+// newPage builds a UserPage from up to limit+1 users, the extra user only
+// signalling that there is a next page.
+func newPage(users []model.User, limit int) *UserPage {
+	p := &UserPage{Users: users}
+	if len(users) > limit {
+		p.Users = users[:limit]
+		p.NextCursor = p.Users[limit-1].Email
+	}
+	if p.Users == nil {
+		p.Users = []model.User{}
+	}
+	return p
+}
@@ -0,0 +1,297 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Reskill-2022/volunteering/jobs"
+	"github.com/Reskill-2022/volunteering/replication"
+)
+
+const (
+	// reconcileBatchSize bounds the number of documents fetched per GetAll.
+	reconcileBatchSize = 100
+
+	// probeDocument is read to measure replica latency for the nearest read
+	// policy. It does not need to exist.
+	probeDocument = "__latency_probe__"
+)
+
+// discard removes entries that need no further replication, either because
+// the write succeeded or because the primary write failed.
+func (u *UserRepository) discard(entries ...replication.Entry) {
+	if u.log == nil {
+		return
+	}
+	for _, entry := range entries {
+		if err := u.log.Complete(entry.ID); err != nil {
+			u.logger.Err(err).Msgf("Replication: failed to complete entry %d", entry.ID)
+		}
+	}
+}
+
+// deferReplication leaves entry in the log to be retried by RetryPending.
+func (u *UserRepository) deferReplication(entry replication.Entry, cause error) {
+	if u.log == nil {
+		u.logger.Warn().Err(cause).Msgf("Replication: %s of %s to %s failed, left for reconciliation", entry.Op, entry.Email, entry.Target)
+		return
+	}
+	u.logger.Warn().Err(cause).Msgf("Replication: %s of %s to %s failed, will retry", entry.Op, entry.Email, entry.Target)
+
+	next := time.Now().UTC().Add(replication.Backoff(time.Second, 0))
+	if err := u.log.Fail(entry.ID, cause, next); err != nil {
+		u.logger.Err(err).Msgf("Replication: failed to record failure of entry %d", entry.ID)
+	}
+}
+
+// jobMirrorWrite is the job kind of a deferred mirror write.
+const jobMirrorWrite = "mirror_write"
+
+type mirrorWrite struct {
+	Entry  uint64 `json:"entry"`
+	Email  string `json:"email"`
+	Target string `json:"target"`
+}
+
+// DeferMirrorWrites runs the mirror writes of the primary-then-async policy
+// as jobs on queue, rather than in a goroutine per request. Their entries stay
+// in the replication log until the job succeeds, so RetryPending still covers
+// jobs that are lost or dead-lettered.
+func (u *UserRepository) DeferMirrorWrites(queue *jobs.Queue) {
+	queue.Register(jobMirrorWrite, func(ctx context.Context, payload json.RawMessage) error {
+		var w mirrorWrite
+		if err := json.Unmarshal(payload, &w); err != nil {
+			return err
+		}
+		if err := u.copyTo(ctx, w.Target, w.Email); err != nil {
+			return err
+		}
+		u.discard(replication.Entry{ID: w.Entry})
+		return nil
+	})
+	u.jobs = queue
+}
+
+func (u *UserRepository) enqueueMirrorWrites(entries []replication.Entry) {
+	for _, entry := range entries {
+		_, err := u.jobs.Enqueue(jobMirrorWrite, mirrorWrite{Entry: entry.ID, Email: entry.Email, Target: entry.Target})
+		if err != nil {
+			// the entry is retried from the replication log
+			u.logger.Err(err).Msgf("Replication: failed to defer write of %s to %s", entry.Email, entry.Target)
+		}
+	}
+}
+
+// RunReplication retries pending mirror writes and probes replica latency
+// every retryInterval, and reconciles the mirrors every reconcileInterval,
+// until ctx is done.
+func (u *UserRepository) RunReplication(ctx context.Context, retryInterval, reconcileInterval time.Duration) {
+	retry := time.NewTicker(retryInterval)
+	defer retry.Stop()
+	reconcile := time.NewTicker(reconcileInterval)
+	defer reconcile.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-retry.C:
+			if err := u.RetryPending(ctx, retryInterval); err != nil {
+				u.logger.Err(err).Msg("Replication: retry pass failed")
+			}
+			u.probe(ctx)
+		case <-reconcile.C:
+			reports, err := u.Reconcile(ctx)
+			if err != nil {
+				u.logger.Err(err).Msg("Replication: reconciliation failed")
+			}
+			for _, report := range reports {
+				u.logger.Info().Msgf("Replication: reconciled %d users on %s, repaired %d, conflicts %d, orphans %d",
+					report.Checked, report.Target, report.Repaired, report.Conflicts, report.Orphans)
+			}
+		}
+	}
+}
+
+// RetryPending re-applies every due entry in the replication log by copying
+// the primary's current document to the entry's target. Copying the current
+// state, rather than replaying the original write, keeps retries idempotent
+// and order-independent.
+func (u *UserRepository) RetryPending(ctx context.Context, base time.Duration) error {
+	if u.log == nil {
+		return nil
+	}
+	pending, err := u.log.Pending()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	replication.RecordBacklog(pending, now)
+
+	for _, entry := range pending {
+		if entry.NextAttempt.After(now) {
+			continue
+		}
+
+		err := u.copyTo(ctx, entry.Target, entry.Email)
+		replication.RecordRetry(err)
+		if err != nil {
+			u.logger.Warn().Err(err).Msgf("Replication: retry %d of %s to %s failed", entry.Attempts+1, entry.Email, entry.Target)
+			if err := u.log.Fail(entry.ID, err, now.Add(replication.Backoff(base, entry.Attempts+1))); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := u.log.Complete(entry.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (u *UserRepository) copyTo(ctx context.Context, target, email string) error {
+	var mirror *replica
+	for _, m := range u.mirrors {
+		if m.name == target {
+			mirror = m
+		}
+	}
+	if mirror == nil {
+		// the replica was removed from the configuration
+		u.logger.Warn().Msgf("Replication: dropping entry for unknown replica %s", target)
+		return nil
+	}
+
+	snap, err := u.primary.client.Collection(collectionName).Doc(email).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		// nothing to replicate; the primary write never landed
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = mirror.client.Collection(collectionName).Doc(email).Set(ctx, snap.Data())
+	return err
+}
+
+func (u *UserRepository) probe(ctx context.Context) {
+	for _, r := range u.replicas {
+		start := time.Now()
+		_, err := r.client.Collection(collectionName).Doc(probeDocument).Get(ctx)
+		if err == nil || status.Code(err) == codes.NotFound {
+			r.observe(time.Since(start))
+		}
+	}
+}
+
+// Reconcile diffs the volunteers collection of every mirror against the
+// primary and overwrites any mirror document that differs. Mirror documents
+// updated more recently than the primary are counted as conflicts, and those
+// missing from the primary as orphans; orphans are reported but left alone.
+func (u *UserRepository) Reconcile(ctx context.Context) ([]replication.Report, error) {
+	var reports []replication.Report
+	for _, m := range u.mirrors {
+		report, err := u.reconcileMirror(ctx, m)
+		if err != nil {
+			return reports, fmt.Errorf("reconcile %s: %w", m.name, err)
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+func (u *UserRepository) reconcileMirror(ctx context.Context, mirror *replica) (replication.Report, error) {
+	report := replication.Report{Target: mirror.name, StartedAt: time.Now().UTC()}
+
+	iter := u.primary.client.Collection(collectionName).Documents(ctx)
+	defer iter.Stop()
+
+	seen := make(map[string]bool)
+	batch := make([]*firestore.DocumentSnapshot, 0, reconcileBatchSize)
+	for {
+		snap, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return report, err
+		}
+
+		seen[snap.Ref.ID] = true
+		batch = append(batch, snap)
+		if len(batch) == reconcileBatchSize {
+			if err := u.reconcileBatch(ctx, mirror, batch, &report); err != nil {
+				return report, err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := u.reconcileBatch(ctx, mirror, batch, &report); err != nil {
+		return report, err
+	}
+
+	refs := mirror.client.Collection(collectionName).DocumentRefs(ctx)
+	for {
+		ref, err := refs.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return report, err
+		}
+		if !seen[ref.ID] {
+			report.Orphans++
+			u.logger.Warn().Msgf("Replication: %s exists in %s but not in %s", ref.ID, mirror.name, u.primary.name)
+		}
+	}
+
+	report.FinishedAt = time.Now().UTC()
+	replication.RecordReconcile(report)
+	return report, nil
+}
+
+func (u *UserRepository) reconcileBatch(ctx context.Context, mirror *replica, primaries []*firestore.DocumentSnapshot, report *replication.Report) error {
+	if len(primaries) == 0 {
+		return nil
+	}
+
+	refs := make([]*firestore.DocumentRef, len(primaries))
+	for i, snap := range primaries {
+		refs[i] = mirror.client.Collection(collectionName).Doc(snap.Ref.ID)
+	}
+
+	secondaries, err := mirror.client.GetAll(ctx, refs)
+	if err != nil {
+		return err
+	}
+
+	for i, primary := range primaries {
+		report.Checked++
+
+		secondary := secondaries[i]
+		if secondary.Exists() && reflect.DeepEqual(primary.Data(), secondary.Data()) {
+			continue
+		}
+		if secondary.Exists() && secondary.UpdateTime.After(primary.UpdateTime) {
+			report.Conflicts++
+			u.logger.Warn().Msgf("Replication: %s was changed in %s after %s, overwriting from %s", primary.Ref.ID, mirror.name, u.primary.name, u.primary.name)
+		}
+
+		if _, err := refs[i].Set(ctx, primary.Data()); err != nil {
+			return err
+		}
+		report.Repaired++
+	}
+
+	return nil
+}
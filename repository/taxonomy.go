@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/model"
+)
+
+const taxonomyCollection = "taxonomy"
+
+// TaxonomyStore persists the option lists managed by admins. Lists that were
+// never saved are absent from GetOptionLists.
+type TaxonomyStore interface {
+	GetOptionLists(ctx context.Context) (map[string][]model.Option, error)
+	SaveOptionList(ctx context.Context, name string, options []model.Option) error
+}
+
+var (
+	_ TaxonomyStore = (*UserRepository)(nil)
+	_ TaxonomyStore = (*BoltUserRepository)(nil)
+	_ TaxonomyStore = (*MemoryUserRepository)(nil)
+)
+
+type optionList struct {
+	Options []model.Option `firestore:"options"`
+}
+
+// GetOptionLists reads from the primary. Option lists are not replicated:
+// they are small, rarely written and have defaults to fall back to.
+func (u *UserRepository) GetOptionLists(ctx context.Context) (map[string][]model.Option, error) {
+	docs, err := u.primary.client.Collection(taxonomyCollection).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, errors.From(err, fmt.Sprintf("%s failed to get option lists", u.primary.name), 500)
+	}
+
+	lists := make(map[string][]model.Option, len(docs))
+	for _, doc := range docs {
+		var list optionList
+		if err := doc.DataTo(&list); err != nil {
+			return nil, errors.From(err, "failed to bind option list", 500)
+		}
+		lists[doc.Ref.ID] = list.Options
+	}
+	return lists, nil
+}
+
+func (u *UserRepository) SaveOptionList(ctx context.Context, name string, options []model.Option) error {
+	u.logger.Debug().Msgf("Firestore: saving option list: %s", name)
+
+	_, err := u.primary.client.Collection(taxonomyCollection).Doc(name).Set(ctx, optionList{Options: options})
+	if err != nil {
+		return errors.From(err, fmt.Sprintf("%s failed to save option list", u.primary.name), 500)
+	}
+	return nil
+}
+
+func (b *BoltUserRepository) GetOptionLists(ctx context.Context) (map[string][]model.Option, error) {
+	lists := make(map[string][]model.Option)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(taxonomyCollection)).ForEach(func(k, v []byte) error {
+			var options []model.Option
+			if err := json.Unmarshal(v, &options); err != nil {
+				return err
+			}
+			lists[string(k)] = options
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errors.From(err, "failed to get option lists", 500)
+	}
+	return lists, nil
+}
+
+func (b *BoltUserRepository) SaveOptionList(ctx context.Context, name string, options []model.Option) error {
+	b.logger.Debug().Msgf("Bolt: saving option list: %s", name)
+
+	raw, err := json.Marshal(options)
+	if err != nil {
+		return errors.From(err, "failed to encode option list", 500)
+	}
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(taxonomyCollection)).Put([]byte(name), raw)
+	})
+	if err != nil {
+		return errors.From(err, "failed to save option list", 500)
+	}
+	return nil
+}
+
+// memoryTaxonomy and memoryForms are kept apart from the users so that
+// admin changes do not contend with user writes.
+type (
+	memoryTaxonomy struct {
+		mu    sync.RWMutex
+		lists map[string][]model.Option
+	}
+
+	memoryForms struct {
+		mu       sync.RWMutex
+		versions []model.Form
+	}
+)
+
+func (m *MemoryUserRepository) GetOptionLists(ctx context.Context) (map[string][]model.Option, error) {
+	m.taxonomy.mu.RLock()
+	defer m.taxonomy.mu.RUnlock()
+
+	lists := make(map[string][]model.Option, len(m.taxonomy.lists))
+	for name, options := range m.taxonomy.lists {
+		lists[name] = append([]model.Option(nil), options...)
+	}
+	return lists, nil
+}
+
+func (m *MemoryUserRepository) SaveOptionList(ctx context.Context, name string, options []model.Option) error {
+	m.logger.Debug().Msgf("Memory: saving option list: %s", name)
+
+	m.taxonomy.mu.Lock()
+	defer m.taxonomy.mu.Unlock()
+
+	if m.taxonomy.lists == nil {
+		m.taxonomy.lists = make(map[string][]model.Option)
+	}
+	m.taxonomy.lists[name] = append([]model.Option(nil), options...)
+	return nil
+}
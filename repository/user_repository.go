@@ -2,71 +2,140 @@ package repository
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"sync"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	firebase "firebase.google.com/go"
-	"github.com/Reskill-2022/volunteering/errors"
-	"github.com/Reskill-2022/volunteering/model"
 	"github.com/rs/zerolog"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Reskill-2022/volunteering/config"
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/jobs"
+	"github.com/Reskill-2022/volunteering/model"
+	"github.com/Reskill-2022/volunteering/replication"
 )
 
 const collectionName = "volunteers"
 
+// asyncWriteTimeout bounds replica writes made after the request returned.
+const asyncWriteTimeout = 30 * time.Second
+
+type (
+	// ReplicaOptions configures one Firestore project.
+	ReplicaOptions struct {
+		Name            string
+		Role            string
+		CredentialsJSON []byte
+	}
+
+	// Options configures the replicas of a UserRepository and how writes and
+	// reads are spread across them.
+	Options struct {
+		Replicas    []ReplicaOptions
+		WritePolicy string
+		ReadPolicy  string
+	}
+
+	replica struct {
+		name   string
+		role   string
+		client *firestore.Client
+
+		mu      sync.Mutex
+		latency time.Duration
+	}
+)
+
+// UserRepository stores users in any number of Firestore projects. Exactly one
+// replica is the primary and is the source of truth; mirrors receive every
+// write according to the write policy and read-only replicas are only read
+// from. Every mirror write is recorded in the replication log before it is
+// attempted so that failed writes are retried in the background. Without a
+// log, as in commands that run next to the server, failed mirror writes are
+// left for reconciliation to repair.
 type UserRepository struct {
-	logger  zerolog.Logger
-	client1 *firestore.Client
-	client2 *firestore.Client
+	logger      zerolog.Logger
+	primary     *replica
+	mirrors     []*replica
+	replicas    []*replica
+	writePolicy string
+	readPolicy  string
+	log         *replication.Log
+	// jobs, when set, runs the mirror writes of the primary-then-async
+	// policy, see DeferMirrorWrites.
+	jobs *jobs.Queue
 }
 
 var _ UserRepositoryInterface = (*UserRepository)(nil)
 
-func NewUserRepository(logger zerolog.Logger) *UserRepository {
+func NewUserRepository(logger zerolog.Logger, log *replication.Log, opts Options) (*UserRepository, error) {
 	r := &UserRepository{
-		logger: logger,
+		logger:      logger,
+		writePolicy: opts.WritePolicy,
+		readPolicy:  opts.ReadPolicy,
+		log:         log,
 	}
 
-	r.client1 = getClient("./service-account-1.json")
-	r.client2 = getClient("./service-account-2.json")
+	for _, ro := range opts.Replicas {
+		client, err := getClient(ro.CredentialsJSON)
+		if err != nil {
+			return nil, fmt.Errorf("replica %s: %w", ro.Name, err)
+		}
 
-	return r
+		rep := &replica{name: ro.Name, role: ro.Role, client: client}
+		r.replicas = append(r.replicas, rep)
+
+		switch ro.Role {
+		case config.RolePrimary:
+			r.primary = rep
+		case config.RoleMirror:
+			r.mirrors = append(r.mirrors, rep)
+		}
+	}
+	if r.primary == nil {
+		return nil, fmt.Errorf("no primary replica configured")
+	}
+
+	return r, nil
 }
 
-func getClient(saFile string) *firestore.Client {
+func getClient(saJSON []byte) (*firestore.Client, error) {
 	ctx := context.Background()
 
-	sa := option.WithCredentialsFile(saFile)
+	sa := option.WithCredentialsJSON(saJSON)
 	app, err := firebase.NewApp(ctx, nil, sa)
 	if err != nil {
-		log.Fatalln(err)
+		return nil, err
 	}
 
-	client, err := app.Firestore(ctx)
-	if err != nil {
-		log.Fatalln(err)
-	}
-
-	return client
+	return app.Firestore(ctx)
 }
 
-func (u *UserRepository) CreateUser(ctx context.Context, user model.User) (*model.User, error) {
+func (u *UserRepository) CreateUser(ctx context.Context, user model.User) (*model.User, bool, error) {
 	u.logger.Debug().Msgf("Firestore: creating user with email: %s", user.Email)
 
 	gotUser, err := u.GetUser(ctx, user.Email)
 	if err == nil || gotUser != nil {
-		return gotUser, nil
+		return gotUser, false, nil
 	}
-
-	if _, err := u.client1.Collection(collectionName).Doc(user.Email).Set(ctx, user); err != nil {
-		return nil, errors.From(err, "client1 failed to create user", 500)
+	if errors.CodeFrom(err) != 404 {
+		return nil, false, err
 	}
 
-	if _, err := u.client2.Collection(collectionName).Doc(user.Email).Set(ctx, user); err != nil {
-		return nil, errors.From(err, "client2 failed to create user", 500)
+	err = u.write(ctx, replication.OpCreate, user.Email, "create user", func(ctx context.Context, doc *firestore.DocumentRef) error {
+		_, err := doc.Set(ctx, user)
+		return err
+	})
+	if err != nil {
+		return nil, false, err
 	}
 
-	return &user, nil
+	return &user, true, nil
 }
 
 func (u *UserRepository) UpdateUser(ctx context.Context, user model.User) (*model.User, error) {
@@ -79,6 +148,10 @@ func (u *UserRepository) UpdateUser(ctx context.Context, user model.User) (*mode
 		{Path: "first_name", Value: user.FirstName},
 		{Path: "last_name", Value: user.LastName},
 		{Path: "photo", Value: user.Photo},
+		{Path: "provider", Value: user.Provider},
+		{Path: "location", Value: user.Location},
+		{Path: "has_experience", Value: user.HasExperience},
+		{Path: "positions", Value: user.Positions},
 		{Path: "state", Value: user.State},
 		{Path: "organization", Value: user.Organization},
 		{Path: "years_of_experience", Value: user.YearsOfExperience},
@@ -87,16 +160,25 @@ func (u *UserRepository) UpdateUser(ctx context.Context, user model.User) (*mode
 		{Path: "convicted", Value: user.Convicted},
 		{Path: "representation", Value: user.Representation},
 		{Path: "provided_name", Value: user.ProvidedName},
+		{Path: "profile_url", Value: user.ProfileURL},
+		{Path: "industries", Value: user.Industries},
+		{Path: "answers", Value: user.Answers},
+		{Path: "form_version", Value: user.FormVersion},
+		{Path: "draft", Value: user.Draft},
 		{Path: "enrolled", Value: user.Enrolled},
+		{Path: "status", Value: user.Status},
+		{Path: "submitted_at", Value: user.SubmittedAt},
+		{Path: "reviews", Value: user.Reviews},
 		{Path: "created_at", Value: user.CreatedAt},
+		{Path: "deactivated", Value: user.Deactivated},
 	}
 
-	if _, err := u.client1.Collection(collectionName).Doc(user.Email).Update(ctx, updates); err != nil {
-		return nil, errors.From(err, "client1 failed to update user data", 500)
-	}
-
-	if _, err := u.client2.Collection(collectionName).Doc(user.Email).Update(ctx, updates); err != nil {
-		return nil, errors.From(err, "client2 failed to update user data", 500)
+	err := u.write(ctx, replication.OpUpdate, user.Email, "update user data", func(ctx context.Context, doc *firestore.DocumentRef) error {
+		_, err := doc.Update(ctx, updates)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &user, nil
@@ -105,16 +187,238 @@ func (u *UserRepository) UpdateUser(ctx context.Context, user model.User) (*mode
 func (u *UserRepository) GetUser(ctx context.Context, email string) (*model.User, error) {
 	u.logger.Debug().Msgf("Firestore: getting user with email: %s", email)
 
-	data, err := u.client1.Collection(collectionName).Doc(email).Get(ctx)
+	switch u.readPolicy {
+	case config.ReadNearest:
+		nearest := u.nearest()
+		user, err := u.getFrom(ctx, nearest, email)
+		if err != nil && nearest != u.primary {
+			// the replica may lag behind or be unavailable
+			return u.getFrom(ctx, u.primary, email)
+		}
+		return user, err
+
+	case config.ReadFallbackOnError:
+		user, err := u.getFrom(ctx, u.primary, email)
+		if err == nil || errors.CodeFrom(err) == 404 {
+			return user, err
+		}
+		for _, r := range u.replicas {
+			if r == u.primary {
+				continue
+			}
+			u.logger.Warn().Err(err).Msgf("Firestore: falling back to %s for %s", r.name, email)
+			if user, err = u.getFrom(ctx, r, email); err == nil || errors.CodeFrom(err) == 404 {
+				return user, err
+			}
+		}
+		return nil, err
+
+	default:
+		return u.getFrom(ctx, u.primary, email)
+	}
+}
+
+// ListUsers reads from the primary, which is the only replica guaranteed to
+// hold every user. Each combination of filters and sort field needs a
+// composite index.
+func (u *UserRepository) ListUsers(ctx context.Context, query UserQuery) (*UserPage, error) {
+	u.logger.Debug().Msgf("Firestore: listing users after: %s", query.Cursor)
+
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	users := u.primary.client.Collection(collectionName)
+	q := users.Query
+
+	f := query.Filter
+	if f.State != "" {
+		q = q.Where("state", "==", f.State)
+	}
+	if f.Organization != "" {
+		q = q.Where("organization", "==", f.Organization)
+	}
+	if f.Enrolled != nil {
+		q = q.Where("enrolled", "==", *f.Enrolled)
+	}
+	if f.Status != "" {
+		q = q.Where("status", "==", f.Status)
+	}
+	if f.VolunteerArea != "" {
+		q = q.Where("volunteer_areas", "array-contains", f.VolunteerArea)
+	}
+	if !f.CreatedAfter.IsZero() {
+		q = q.Where("created_at", ">=", f.CreatedAfter)
+	}
+	if !f.CreatedBefore.IsZero() {
+		q = q.Where("created_at", "<", f.CreatedBefore)
+	}
+
+	dir := firestore.Asc
+	if query.Desc {
+		dir = firestore.Desc
+	}
+	if query.Sort != "" && query.Sort != SortEmail {
+		q = q.OrderBy(query.Sort, dir)
+	}
+	q = q.OrderBy(firestore.DocumentID, dir)
+
+	if query.Cursor != "" {
+		after, err := users.Doc(query.Cursor).Get(ctx)
+		if status.Code(err) == codes.NotFound {
+			return nil, errors.From(err, "Invalid Cursor", 400)
+		}
+		if err != nil {
+			return nil, errors.From(err, fmt.Sprintf("%s failed to list users", u.primary.name), 500)
+		}
+		q = q.StartAfter(after)
+	}
+
+	limit := query.limit()
+	docs, err := q.Limit(limit + 1).Documents(ctx).GetAll()
 	if err != nil {
-		return nil, errors.From(err, "User Account Not Found", 404)
+		return nil, errors.From(err, fmt.Sprintf("%s failed to list users", u.primary.name), 500)
+	}
+
+	page := make([]model.User, 0, len(docs))
+	for _, doc := range docs {
+		user, err := decodeUser(doc)
+		if err != nil {
+			return nil, err
+		}
+		page = append(page, *user)
 	}
 
-	user := model.User{}
-	err = data.DataTo(&user)
+	return newPage(page, limit), nil
+}
+
+func (u *UserRepository) getFrom(ctx context.Context, r *replica, email string) (*model.User, error) {
+	start := time.Now()
+	data, err := r.client.Collection(collectionName).Doc(email).Get(ctx)
+	r.observe(time.Since(start))
+
+	if status.Code(err) == codes.NotFound {
+		return nil, errors.From(err, "User Account Not Found", 404)
+	}
 	if err != nil {
-		return nil, errors.From(err, "failed to bind user data", 500)
+		return nil, errors.From(err, fmt.Sprintf("%s failed to get user", r.name), 500)
 	}
 
-	return &user, nil
+	return decodeUser(data)
+}
+
+// write applies a mutation to the primary and then to every mirror according
+// to the write policy:
+//
+//   - all: every mirror is written before returning and any failure fails the request.
+//   - quorum: the primary and a majority of all writable replicas must succeed.
+//   - primary-then-async: mirrors are written in the background.
+//
+// Failed mirror writes are always left in the replication log to be retried.
+func (u *UserRepository) write(ctx context.Context, op replication.Op, email, action string, apply func(context.Context, *firestore.DocumentRef) error) error {
+	entries := make([]replication.Entry, 0, len(u.mirrors))
+	for _, m := range u.mirrors {
+		entry := replication.Entry{Op: op, Email: email, Target: m.name}
+		if u.log == nil {
+			entries = append(entries, entry)
+			continue
+		}
+		entry, err := u.log.Append(entry)
+		if err != nil {
+			u.discard(entries...)
+			return errors.From(err, "failed to record user mutation", 500)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := apply(ctx, u.primary.client.Collection(collectionName).Doc(email)); err != nil {
+		u.discard(entries...)
+		return errors.From(err, fmt.Sprintf("%s failed to %s", u.primary.name, action), 500)
+	}
+
+	if u.writePolicy == config.WritePrimaryThenAsync && u.jobs != nil {
+		u.enqueueMirrorWrites(entries)
+		return nil
+	}
+	if u.writePolicy == config.WritePrimaryThenAsync {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), asyncWriteTimeout)
+			defer cancel()
+			u.writeMirrors(ctx, entries, email, apply)
+		}()
+		return nil
+	}
+
+	failures := u.writeMirrors(ctx, entries, email, apply)
+	if len(failures) == 0 {
+		return nil
+	}
+
+	if u.writePolicy == config.WriteQuorum {
+		writable := len(u.mirrors) + 1
+		if acked := writable - len(failures); acked > writable/2 {
+			return nil
+		}
+	}
+	return errors.From(failures[0], fmt.Sprintf("failed to %s on %d replica(s)", action, len(failures)), 500)
+}
+
+// writeMirrors applies a mutation to every mirror concurrently and returns
+// the errors of those that failed.
+func (u *UserRepository) writeMirrors(ctx context.Context, entries []replication.Entry, email string, apply func(context.Context, *firestore.DocumentRef) error) []error {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		failures []error
+	)
+
+	for i, m := range u.mirrors {
+		wg.Add(1)
+		go func(m *replica, entry replication.Entry) {
+			defer wg.Done()
+
+			if err := apply(ctx, m.client.Collection(collectionName).Doc(email)); err != nil {
+				u.deferReplication(entry, err)
+				mu.Lock()
+				failures = append(failures, fmt.Errorf("%s: %w", m.name, err))
+				mu.Unlock()
+				return
+			}
+			u.discard(entry)
+		}(m, entries[i])
+	}
+	wg.Wait()
+
+	return failures
+}
+
+// nearest returns the replica with the lowest observed read latency.
+func (u *UserRepository) nearest() *replica {
+	best := u.primary
+	bestLatency := best.observed()
+	for _, r := range u.replicas {
+		if l := r.observed(); l > 0 && (bestLatency == 0 || l < bestLatency) {
+			best, bestLatency = r, l
+		}
+	}
+	return best
+}
+
+// observe folds d into an exponentially weighted moving average of the
+// replica's read latency.
+func (r *replica) observe(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.latency == 0 {
+		r.latency = d
+		return
+	}
+	r.latency = (r.latency*7 + d) / 8
+}
+
+func (r *replica) observed() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.latency
 }
@@ -0,0 +1,423 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/Reskill-2022/volunteering/audit"
+	"github.com/Reskill-2022/volunteering/config"
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/model"
+)
+
+func testBackends(t *testing.T) map[string]UserRepositoryInterface {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open bolt database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	boltRepo, err := NewBoltUserRepository(zerolog.Nop(), db)
+	if err != nil {
+		t.Fatalf("failed to create bolt repository: %v", err)
+	}
+
+	return map[string]UserRepositoryInterface{
+		"bolt":   boltRepo,
+		"memory": NewMemoryUserRepository(zerolog.Nop()),
+	}
+}
+
+func TestUserRepositorySemantics(t *testing.T) {
+	ctx := context.Background()
+
+	for name, repo := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			if _, err := repo.GetUser(ctx, "missing@example.com"); errors.CodeFrom(err) != 404 {
+				t.Errorf("expected 404 for missing user, got %v", err)
+			}
+
+			if _, err := repo.UpdateUser(ctx, model.User{Email: "missing@example.com"}); err == nil {
+				t.Errorf("expected update of missing user to fail")
+			}
+
+			created, ok, err := repo.CreateUser(ctx, model.User{Email: "jane@example.com", Name: "Jane Doe"})
+			if err != nil {
+				t.Fatalf("unexpected error creating user: %v", err)
+			}
+			if !ok || created.Name != "Jane Doe" {
+				t.Errorf("expected 'Jane Doe' to be created, got '%s' (created %t)", created.Name, ok)
+			}
+
+			again, ok, err := repo.CreateUser(ctx, model.User{Email: "jane@example.com", Name: "Someone Else"})
+			if err != nil {
+				t.Fatalf("unexpected error re-creating user: %v", err)
+			}
+			if ok || again.Name != "Jane Doe" {
+				t.Errorf("expected existing record to be returned, got name '%s' (created %t)", again.Name, ok)
+			}
+
+			created.State = "Texas"
+			created.Enrolled = true
+			if _, err := repo.UpdateUser(ctx, *created); err != nil {
+				t.Fatalf("unexpected error updating user: %v", err)
+			}
+
+			got, err := repo.GetUser(ctx, "jane@example.com")
+			if err != nil {
+				t.Fatalf("unexpected error getting user: %v", err)
+			}
+			if got.State != "Texas" || !got.Enrolled {
+				t.Errorf("expected update to be persisted, got %+v", got)
+			}
+		})
+	}
+}
+
+func TestUserRepositoryListUsers(t *testing.T) {
+	ctx := context.Background()
+
+	for name, repo := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			page, err := repo.ListUsers(ctx, UserQuery{})
+			if err != nil {
+				t.Fatalf("unexpected error listing users: %v", err)
+			}
+			if len(page.Users) != 0 || page.NextCursor != "" {
+				t.Errorf("expected an empty last page, got %+v", page)
+			}
+
+			for i := 0; i < 5; i++ {
+				if _, _, err := repo.CreateUser(ctx, model.User{Email: fmt.Sprintf("user%d@example.com", 4-i)}); err != nil {
+					t.Fatalf("unexpected error creating user: %v", err)
+				}
+			}
+
+			var emails []string
+			query := UserQuery{Limit: 2}
+			for pages := 0; ; pages++ {
+				if pages > 3 {
+					t.Fatalf("expected 3 pages, still paging at cursor '%s'", query.Cursor)
+				}
+				page, err := repo.ListUsers(ctx, query)
+				if err != nil {
+					t.Fatalf("unexpected error listing users: %v", err)
+				}
+				for _, u := range page.Users {
+					emails = append(emails, u.Email)
+				}
+				if page.NextCursor == "" {
+					break
+				}
+				query.Cursor = page.NextCursor
+			}
+
+			want := "user0@example.com user1@example.com user2@example.com user3@example.com user4@example.com"
+			if got := fmt.Sprint(emails); got != "["+want+"]" {
+				t.Errorf("expected users in email order, got %s", got)
+			}
+		})
+	}
+}
+
+func TestUserRepositoryListUsersFilters(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC)
+	enrolled := true
+
+	seed := []model.User{
+		{Email: "a@example.com", Name: "Ada", State: "Texas", VolunteerAreas: model.StringList{"Mentoring", "Design"}, Enrolled: true, CreatedAt: day.Add(48 * time.Hour)},
+		{Email: "b@example.com", Name: "Bo", State: "Texas", VolunteerAreas: model.StringList{"Design"}, Enrolled: false, CreatedAt: day},
+		{Email: "c@example.com", Name: "Cy", State: "Ohio", VolunteerAreas: model.StringList{"Mentoring"}, Enrolled: true, CreatedAt: day.Add(24 * time.Hour)},
+		{Email: "d@example.com", Name: "Di", State: "Texas", VolunteerAreas: model.StringList{"mentoring"}, Enrolled: true, CreatedAt: day.Add(24 * time.Hour)},
+	}
+
+	testCases := []struct {
+		name  string
+		query UserQuery
+		want  string
+	}{
+		{"state", UserQuery{Filter: UserFilter{State: "Texas"}}, "[a b d]"},
+		{"enrolled area", UserQuery{Filter: UserFilter{Enrolled: &enrolled, VolunteerArea: "Mentoring"}}, "[a c]"},
+		{"sort by name desc", UserQuery{Sort: SortName, Desc: true}, "[d c b a]"},
+		{"created range", UserQuery{Sort: SortCreatedAt, Filter: UserFilter{CreatedAfter: day.Add(time.Hour), CreatedBefore: day.Add(72 * time.Hour)}}, "[c d a]"},
+		{"created desc paged", UserQuery{Sort: SortCreatedAt, Desc: true, Limit: 2, Cursor: "d"}, "[c b]"},
+	}
+
+	for name, repo := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, u := range seed {
+				if _, _, err := repo.CreateUser(ctx, u); err != nil {
+					t.Fatalf("unexpected error creating user: %v", err)
+				}
+			}
+
+			for _, tc := range testCases {
+				query := tc.query
+				if query.Cursor != "" {
+					query.Cursor += "@example.com"
+				}
+				page, err := repo.ListUsers(ctx, query)
+				if err != nil {
+					t.Errorf("%s: unexpected error: %v", tc.name, err)
+					continue
+				}
+				var got []string
+				for _, u := range page.Users {
+					got = append(got, strings.TrimSuffix(u.Email, "@example.com"))
+				}
+				if fmt.Sprint(got) != tc.want {
+					t.Errorf("%s: got %v, want %s", tc.name, got, tc.want)
+				}
+			}
+
+			invalid := []UserQuery{
+				{Sort: "phone"},
+				{Filter: UserFilter{CreatedAfter: day}},
+				{Cursor: "missing@example.com"},
+			}
+			for _, q := range invalid {
+				if _, err := repo.ListUsers(ctx, q); errors.CodeFrom(err) != 400 {
+					t.Errorf("expected 400 for %+v, got %v", q, err)
+				}
+			}
+		})
+	}
+}
+
+func TestUserRepositoryConcurrentCreate(t *testing.T) {
+	ctx := context.Background()
+
+	for name, repo := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			var (
+				wg      sync.WaitGroup
+				created int32
+			)
+			names := make(chan string, 20)
+
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					user, ok, err := repo.CreateUser(ctx, model.User{Email: "race@example.com", Name: fmt.Sprintf("User %d", i)})
+					if err != nil {
+						t.Errorf("unexpected error creating user: %v", err)
+						return
+					}
+					if ok {
+						atomic.AddInt32(&created, 1)
+					}
+					names <- user.Name
+				}(i)
+			}
+			wg.Wait()
+			close(names)
+			if created != 1 {
+				t.Errorf("expected exactly one create to report creation, got %d", created)
+			}
+
+			stored, err := repo.GetUser(ctx, "race@example.com")
+			if err != nil {
+				t.Fatalf("unexpected error getting user: %v", err)
+			}
+			for name := range names {
+				if name != stored.Name {
+					t.Errorf("expected every create to return the stored record '%s', got '%s'", stored.Name, name)
+				}
+			}
+		})
+	}
+}
+
+func TestBoltMigrateVolunteerLists(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open bolt database: %v", err)
+	}
+	defer db.Close()
+
+	repo, err := NewBoltUserRepository(zerolog.Nop(), db)
+	if err != nil {
+		t.Fatalf("failed to create bolt repository: %v", err)
+	}
+	if _, _, err := repo.CreateUser(ctx, model.User{Email: "new@example.com", VolunteerAreas: model.StringList{"Design"}}); err != nil {
+		t.Fatalf("unexpected error creating user: %v", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		legacy := `{"email": "old@example.com", "volunteer_areas": "Mentoring, Design", "volunteer_means": "Remote"}`
+		return tx.Bucket([]byte(collectionName)).Put([]byte("old@example.com"), []byte(legacy))
+	})
+	if err != nil {
+		t.Fatalf("failed to seed legacy user: %v", err)
+	}
+
+	got, err := repo.GetUser(ctx, "old@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error reading legacy user: %v", err)
+	}
+	if fmt.Sprint([]string(got.VolunteerAreas)) != "[Mentoring Design]" {
+		t.Errorf("expected legacy areas to be split, got %q", got.VolunteerAreas)
+	}
+
+	for _, want := range []struct {
+		dryRun bool
+		n      int
+	}{{true, 1}, {false, 1}, {false, 0}} {
+		n, err := repo.MigrateVolunteerLists(ctx, want.dryRun)
+		if err != nil {
+			t.Fatalf("unexpected error migrating: %v", err)
+		}
+		if n != want.n {
+			t.Errorf("MigrateVolunteerLists(dryRun %t) = %d, want %d", want.dryRun, n, want.n)
+		}
+	}
+}
+
+func TestFormStore(t *testing.T) {
+	ctx := context.Background()
+
+	for name, repo := range testBackends(t) {
+		store := repo.(FormStore)
+
+		if _, err := store.LatestForm(ctx); errors.CodeFrom(err) != 404 {
+			t.Errorf("%s: expected 404 without forms, got %v", name, err)
+		}
+
+		for _, version := range []int{2, 10, 3} {
+			if err := store.CreateForm(ctx, model.Form{Version: version}); err != nil {
+				t.Fatalf("%s: failed to create form %d: %v", name, version, err)
+			}
+		}
+		if err := store.CreateForm(ctx, model.Form{Version: 3}); errors.CodeFrom(err) != 409 {
+			t.Errorf("%s: expected 409 for an existing version, got %v", name, err)
+		}
+
+		latest, err := store.LatestForm(ctx)
+		if err != nil || latest.Version != 10 {
+			t.Errorf("%s: expected version 10 to be the latest, got %+v, %v", name, latest, err)
+		}
+		if form, err := store.GetForm(ctx, 3); err != nil || form.Version != 3 {
+			t.Errorf("%s: expected version 3, got %+v, %v", name, form, err)
+		}
+		if _, err := store.GetForm(ctx, 4); errors.CodeFrom(err) != 404 {
+			t.Errorf("%s: expected 404 for a missing version, got %v", name, err)
+		}
+	}
+}
+
+func TestAuditedUserRepository(t *testing.T) {
+	for name, repo := range testBackends(t) {
+		audited := NewAuditedUserRepository(zerolog.Nop(), repo, repo.(AuditStore))
+		ctx := audit.WithEndpoint(audit.WithActor(context.Background(), audit.Actor{Name: "ops", Role: "admin"}), "PATCH /admin/users/:email")
+
+		user := model.User{Email: "jane@example.com", Name: "Jane"}
+		if _, _, err := audited.CreateUser(ctx, user); err != nil {
+			t.Fatalf("%s: failed to create user: %v", name, err)
+		}
+		// signing in again changes nothing
+		if _, _, err := audited.CreateUser(ctx, user); err != nil {
+			t.Fatalf("%s: failed to create user: %v", name, err)
+		}
+
+		user.State = "Texas"
+		if _, err := audited.UpdateUser(ctx, user); err != nil {
+			t.Fatalf("%s: failed to update user: %v", name, err)
+		}
+		if _, err := audited.UpdateUser(ctx, user); err != nil {
+			t.Fatalf("%s: failed to update user: %v", name, err)
+		}
+		if _, err := audited.UpdateUser(ctx, model.User{Email: "missing@example.com"}); errors.CodeFrom(err) != 404 {
+			t.Errorf("%s: expected 404 for a missing user, got %v", name, err)
+		}
+
+		// a volunteer whose email prefixes Jane's
+		if _, _, err := audited.CreateUser(ctx, model.User{Email: "jane@example.co"}); err != nil {
+			t.Fatalf("%s: failed to create user: %v", name, err)
+		}
+
+		entries, err := repo.(AuditStore).ListAudit(ctx, "jane@example.com")
+		if err != nil {
+			t.Fatalf("%s: failed to list audit: %v", name, err)
+		}
+		if len(entries) != 2 {
+			t.Fatalf("%s: expected 2 entries, got %+v", name, entries)
+		}
+		if e := entries[0]; e.Action != model.AuditCreate || e.Actor != "ops" || e.Role != "admin" || e.Endpoint != "PATCH /admin/users/:email" {
+			t.Errorf("%s: unexpected entry %+v", name, e)
+		}
+		want := []model.Change{{Field: "state", Before: "", After: "Texas"}}
+		if e := entries[1]; e.Action != model.AuditUpdate || fmt.Sprint(e.Changes) != fmt.Sprint(want) {
+			t.Errorf("%s: unexpected entry %+v", name, e)
+		}
+	}
+}
+
+func TestUserRepositoryReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	for name, repo := range testBackends(t) {
+		user := model.User{
+			Email:          "jane@example.com",
+			VolunteerAreas: model.StringList{"Mentoring"},
+			Draft:          &model.Draft{FormVersion: 1, Answers: map[string]interface{}{"state": "Texas"}},
+		}
+		if _, _, err := repo.CreateUser(ctx, user); err != nil {
+			t.Fatalf("%s: failed to create user: %v", name, err)
+		}
+		user.Draft.Answers["state"] = "Ohio"
+
+		got, err := repo.GetUser(ctx, user.Email)
+		if err != nil {
+			t.Fatalf("%s: failed to get user: %v", name, err)
+		}
+		got.Draft.Answers["state"] = "Utah"
+		got.VolunteerAreas[0] = "Design"
+
+		got, err = repo.GetUser(ctx, user.Email)
+		if err != nil {
+			t.Fatalf("%s: failed to get user: %v", name, err)
+		}
+		if got.Draft.Answers["state"] != "Texas" || got.VolunteerAreas[0] != "Mentoring" {
+			t.Errorf("%s: stored user changed through a copy: %+v", name, got)
+		}
+	}
+}
+
+func TestNewCommandContainer(t *testing.T) {
+	cfg := &config.Config{
+		Storage: config.Storage{Driver: config.DriverMemory},
+		Jobs:    config.Jobs{Path: filepath.Join(t.TempDir(), "jobs.db")},
+	}
+
+	// the server holds the job queue
+	rc, err := NewContainer(zerolog.Nop(), cfg)
+	if err != nil {
+		t.Fatalf("NewContainer: %v", err)
+	}
+	defer rc.Jobs.Close()
+
+	start := time.Now()
+	cmd, err := NewCommandContainer(zerolog.Nop(), cfg)
+	if err != nil {
+		t.Fatalf("NewCommandContainer: %v", err)
+	}
+	if cmd.Jobs != nil || len(cmd.background) != 0 {
+		t.Errorf("expected a command container without jobs or background work, got %+v", cmd)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("NewCommandContainer waited %s for the job queue", elapsed)
+	}
+}
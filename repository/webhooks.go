@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"cloud.google.com/go/firestore"
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/model"
+)
+
+const deliveriesCollection = "webhook_deliveries"
+
+var errDeliveryNotFound = errors.New("Delivery Not Found", 404)
+
+// DeliveryStore is the log of webhook deliveries. Delivery IDs sort in the
+// order deliveries were created.
+type DeliveryStore interface {
+	// SaveDelivery creates or replaces the delivery with d's ID.
+	SaveDelivery(ctx context.Context, d model.Delivery) error
+	GetDelivery(ctx context.Context, id string) (*model.Delivery, error)
+	// ListDeliveries returns up to limit deliveries, newest first, to
+	// webhook or to every webhook if it is empty.
+	ListDeliveries(ctx context.Context, webhook string, limit int) ([]model.Delivery, error)
+}
+
+var (
+	_ DeliveryStore = (*UserRepository)(nil)
+	_ DeliveryStore = (*BoltUserRepository)(nil)
+	_ DeliveryStore = (*MemoryUserRepository)(nil)
+)
+
+// SaveDelivery writes to the primary only. Deliveries are not replicated.
+func (u *UserRepository) SaveDelivery(ctx context.Context, d model.Delivery) error {
+	_, err := u.primary.client.Collection(deliveriesCollection).Doc(d.ID).Set(ctx, d)
+	if err != nil {
+		return errors.From(err, fmt.Sprintf("%s failed to save delivery", u.primary.name), 500)
+	}
+	return nil
+}
+
+func (u *UserRepository) GetDelivery(ctx context.Context, id string) (*model.Delivery, error) {
+	doc, err := u.primary.client.Collection(deliveriesCollection).Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, errDeliveryNotFound
+	}
+	if err != nil {
+		return nil, errors.From(err, fmt.Sprintf("%s failed to get delivery", u.primary.name), 500)
+	}
+
+	var d model.Delivery
+	if err := doc.DataTo(&d); err != nil {
+		return nil, errors.From(err, "failed to bind delivery", 500)
+	}
+	return &d, nil
+}
+
+// ListDeliveries filtered by webhook needs a composite index on webhook and
+// the document ID.
+func (u *UserRepository) ListDeliveries(ctx context.Context, webhook string, limit int) ([]model.Delivery, error) {
+	q := u.primary.client.Collection(deliveriesCollection).Query
+	if webhook != "" {
+		q = q.Where("webhook", "==", webhook)
+	}
+	docs, err := q.OrderBy(firestore.DocumentID, firestore.Desc).Limit(limit).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, errors.From(err, fmt.Sprintf("%s failed to list deliveries", u.primary.name), 500)
+	}
+
+	deliveries := make([]model.Delivery, 0, len(docs))
+	for _, doc := range docs {
+		var d model.Delivery
+		if err := doc.DataTo(&d); err != nil {
+			return nil, errors.From(err, "failed to bind delivery", 500)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, nil
+}
+
+func (b *BoltUserRepository) SaveDelivery(ctx context.Context, d model.Delivery) error {
+	raw, err := json.Marshal(d)
+	if err != nil {
+		return errors.From(err, "failed to encode delivery", 500)
+	}
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(deliveriesCollection)).Put([]byte(d.ID), raw)
+	})
+	if err != nil {
+		return errors.From(err, "failed to save delivery", 500)
+	}
+	return nil
+}
+
+func (b *BoltUserRepository) GetDelivery(ctx context.Context, id string) (*model.Delivery, error) {
+	var raw []byte
+	_ = b.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket([]byte(deliveriesCollection)).Get([]byte(id)); v != nil {
+			raw = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	if raw == nil {
+		return nil, errDeliveryNotFound
+	}
+
+	var d model.Delivery
+	if err := json.Unmarshal(raw, &d); err != nil {
+		return nil, errors.From(err, "failed to decode delivery", 500)
+	}
+	return &d, nil
+}
+
+func (b *BoltUserRepository) ListDeliveries(ctx context.Context, webhook string, limit int) ([]model.Delivery, error) {
+	deliveries := []model.Delivery{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(deliveriesCollection)).Cursor()
+		for k, v := c.Last(); k != nil && len(deliveries) < limit; k, v = c.Prev() {
+			var d model.Delivery
+			if err := json.Unmarshal(v, &d); err != nil {
+				return err
+			}
+			if webhook == "" || d.Webhook == webhook {
+				deliveries = append(deliveries, d)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.From(err, "failed to list deliveries", 500)
+	}
+	return deliveries, nil
+}
+
+type memoryDeliveries struct {
+	mu         sync.RWMutex
+	deliveries map[string]model.Delivery
+}
+
+func (m *MemoryUserRepository) SaveDelivery(ctx context.Context, d model.Delivery) error {
+	m.deliveries.mu.Lock()
+	defer m.deliveries.mu.Unlock()
+
+	if m.deliveries.deliveries == nil {
+		m.deliveries.deliveries = make(map[string]model.Delivery)
+	}
+	m.deliveries.deliveries[d.ID] = d
+	return nil
+}
+
+func (m *MemoryUserRepository) GetDelivery(ctx context.Context, id string) (*model.Delivery, error) {
+	m.deliveries.mu.RLock()
+	defer m.deliveries.mu.RUnlock()
+
+	d, ok := m.deliveries.deliveries[id]
+	if !ok {
+		return nil, errDeliveryNotFound
+	}
+	return &d, nil
+}
+
+func (m *MemoryUserRepository) ListDeliveries(ctx context.Context, webhook string, limit int) ([]model.Delivery, error) {
+	m.deliveries.mu.RLock()
+	defer m.deliveries.mu.RUnlock()
+
+	deliveries := []model.Delivery{}
+	for _, d := range m.deliveries.deliveries {
+		if webhook == "" || d.Webhook == webhook {
+			deliveries = append(deliveries, d)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].ID > deliveries[j].ID })
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
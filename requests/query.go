@@ -0,0 +1,78 @@
+package requests
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/repository"
+	"github.com/Reskill-2022/volunteering/review"
+)
+
+// ParseUserQuery reads a repository.UserQuery from a query string:
+//
+//	?state=&organization=&enrolled=true&status=&volunteer_area=&created_after=2022-08-01
+//	&created_before=&sort=-created_at&limit=50&cursor=
+//
+// A leading "-" on sort sorts in descending order. Dates are RFC 3339 times
+// or YYYY-MM-DD days in UTC. state, organization and volunteer_area match
+// exactly, case included, on every driver.
+func ParseUserQuery(values url.Values) (repository.UserQuery, error) {
+	query := repository.UserQuery{
+		Filter: repository.UserFilter{
+			State:         values.Get("state"),
+			Organization:  values.Get("organization"),
+			Status:        values.Get("status"),
+			VolunteerArea: values.Get("volunteer_area"),
+		},
+		Cursor: values.Get("cursor"),
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return query, errors.New("Limit must be a positive number", 400)
+		}
+		query.Limit = n
+	}
+
+	if enrolled := values.Get("enrolled"); enrolled != "" {
+		b, err := strconv.ParseBool(enrolled)
+		if err != nil {
+			return query, errors.New("Enrolled must be true or false", 400)
+		}
+		query.Filter.Enrolled = &b
+	}
+
+	if query.Filter.Status != "" {
+		if err := review.Valid(query.Filter.Status); err != nil {
+			return query, err
+		}
+	}
+
+	var err error
+	if query.Filter.CreatedAfter, err = parseDate(values.Get("created_after")); err != nil {
+		return query, errors.New("Invalid created_after. Use YYYY-MM-DD or an RFC 3339 time", 400)
+	}
+	if query.Filter.CreatedBefore, err = parseDate(values.Get("created_before")); err != nil {
+		return query, errors.New("Invalid created_before. Use YYYY-MM-DD or an RFC 3339 time", 400)
+	}
+
+	sort := values.Get("sort")
+	query.Desc = strings.HasPrefix(sort, "-")
+	query.Sort = strings.TrimPrefix(sort, "-")
+
+	return query, query.Validate()
+}
+
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
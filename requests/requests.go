@@ -1,19 +1,82 @@
 package requests
 
+import (
+	"time"
+
+	"github.com/Reskill-2022/volunteering/model"
+)
+
 type (
 	CreateUserRequest struct {
 		AuthCode    string `json:"code"`
 		RedirectURI string `json:"redirect_uri"`
+		// Provider is the identity provider that issued AuthCode. Defaults to
+		// "linkedin" for older clients.
+		Provider string `json:"provider,omitempty"`
+	}
+
+	// AdminUpdateUserRequest edits a volunteer's application. Only the
+	// fields that are set are changed.
+	AdminUpdateUserRequest struct {
+		Phone             *string          `json:"phone"`
+		State             *string          `json:"state"`
+		Organization      *string          `json:"organization"`
+		YearsOfExperience *string          `json:"years_of_experience"`
+		VolunteerAreas    model.StringList `json:"volunteer_areas,omitempty"`
+		VolunteerMeans    model.StringList `json:"volunteer_means,omitempty"`
+		Convicted         *bool            `json:"convicted"`
+		Representation    *string          `json:"representation"`
+		ProvidedName      *string          `json:"provided_name"`
+		ProfileURL        *string          `json:"profile_url"`
+		// Industries replaces the list, an empty one clears it.
+		Industries *model.StringList `json:"industries"`
+		Enrolled   *bool             `json:"enrolled"`
+	}
+
+	// ReviewRequest moves an application to Status. Note is optional.
+	ReviewRequest struct {
+		Status string `json:"status"`
+		Note   string `json:"note"`
 	}
 
-	UpdateUserRequest struct {
-		State             string   `json:"state"`
-		Organization      string   `json:"organization"`
-		YearsOfExperience string   `json:"years_of_experience"`
-		VolunteerAreas    []string `json:"volunteer_areas,omitempty"`
-		VolunteerMeans    []string `json:"volunteer_means,omitempty"`
-		Convicted         *bool    `json:"convicted"`
-		Representation    string   `json:"representation"`
-		ProvidedName      string   `json:"provided_name"`
+	// WithdrawRequest withdraws the volunteer's own application.
+	WithdrawRequest struct {
+		Note string `json:"note"`
+	}
+
+	// CreateFormRequest publishes a new version of the application form.
+	CreateFormRequest struct {
+		Fields []model.Field `json:"fields"`
+	}
+
+	// SessionResponse is returned on sign-in. The user's fields are inlined
+	// so that older clients reading the user from the payload keep working.
+	SessionResponse struct {
+		*model.User
+		Token          string    `json:"token"`
+		TokenExpiresAt time.Time `json:"token_expires_at"`
 	}
 )
+
+// Answers names the application answers r changes, by form field name.
+func (r AdminUpdateUserRequest) Answers() []string {
+	set := map[string]bool{
+		"state":               r.State != nil,
+		"organization":        r.Organization != nil,
+		"years_of_experience": r.YearsOfExperience != nil,
+		"volunteer_areas":     r.VolunteerAreas != nil,
+		"volunteer_means":     r.VolunteerMeans != nil,
+		"convicted":           r.Convicted != nil,
+		"representation":      r.Representation != nil,
+		"provided_name":       r.ProvidedName != nil,
+		"profile_url":         r.ProfileURL != nil,
+		"industries":          r.Industries != nil,
+	}
+	var names []string
+	for name, ok := range set {
+		if ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
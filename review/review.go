@@ -0,0 +1,109 @@
+// Package review moves applications through the review workflow:
+//
+//	submitted -> under_review -> approved | rejected
+//
+// An application under review can be returned to the queue, decisions can be
+// reopened for review, and volunteers can withdraw at any point before they
+// are rejected. Withdrawal is final.
+package review
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/model"
+)
+
+// RoleVolunteer is the Review.Role of changes made by the volunteer.
+const RoleVolunteer = "volunteer"
+
+// MaxNoteLength bounds reviewer notes, in characters.
+const MaxNoteLength = 2000
+
+// Statuses lists every application status.
+var Statuses = []string{
+	model.StatusSubmitted, model.StatusUnderReview, model.StatusApproved,
+	model.StatusRejected, model.StatusWithdrawn,
+}
+
+// transitions maps each status to the statuses it can move to.
+var transitions = map[string][]string{
+	model.StatusSubmitted:   {model.StatusUnderReview, model.StatusWithdrawn},
+	model.StatusUnderReview: {model.StatusSubmitted, model.StatusApproved, model.StatusRejected, model.StatusWithdrawn},
+	model.StatusApproved:    {model.StatusUnderReview, model.StatusWithdrawn},
+	model.StatusRejected:    {model.StatusUnderReview},
+	model.StatusWithdrawn:   nil,
+}
+
+// StatusOf returns the status of user's application, empty if they have not
+// applied. Applications submitted before the workflow existed are submitted.
+func StatusOf(user model.User) string {
+	if user.Status == "" && user.Enrolled {
+		return model.StatusSubmitted
+	}
+	return user.Status
+}
+
+// Allowed reports whether an application can move from one status to another.
+func Allowed(from, to string) bool {
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Valid checks that status is one of Statuses.
+func Valid(status string) error {
+	if _, ok := transitions[status]; !ok {
+		msg := fmt.Sprintf("Invalid Status '%s'. Use one of: %s", status, strings.Join(Statuses, ", "))
+		return errors.New(msg, 400)
+	}
+	return nil
+}
+
+// Submit marks user's application as submitted at.
+func Submit(user *model.User, at time.Time) {
+	user.Status = model.StatusSubmitted
+	user.SubmittedAt = &at
+	user.Reviews = append(user.Reviews, model.Review{
+		To:       model.StatusSubmitted,
+		Reviewer: user.Email,
+		Role:     RoleVolunteer,
+		At:       at,
+	})
+}
+
+// Transition moves user's application to status to, recording who moved it
+// and why.
+func Transition(user *model.User, to, reviewer, role, note string, at time.Time) error {
+	if err := Valid(to); err != nil {
+		return err
+	}
+	note = strings.TrimSpace(note)
+	if len([]rune(note)) > MaxNoteLength {
+		return errors.New(fmt.Sprintf("Note Too Long. Keep it under %d characters", MaxNoteLength), 400)
+	}
+
+	from := StatusOf(*user)
+	if from == "" {
+		return errors.New("Application Not Submitted", 409)
+	}
+	if !Allowed(from, to) {
+		return errors.New(fmt.Sprintf("Cannot move an application from %s to %s", from, to), 409)
+	}
+
+	user.Status = to
+	user.Reviews = append(user.Reviews, model.Review{
+		From:     from,
+		To:       to,
+		Note:     note,
+		Reviewer: reviewer,
+		Role:     role,
+		At:       at,
+	})
+	return nil
+}
@@ -0,0 +1,64 @@
+package review
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/model"
+)
+
+func TestTransition(t *testing.T) {
+	at := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	user := model.User{Email: "jane@example.com"}
+	if err := Transition(&user, model.StatusApproved, "Ada", "coordinator", "", at); errors.CodeFrom(err) != 409 {
+		t.Fatalf("expected a 409 before submission, got %v", err)
+	}
+
+	Submit(&user, at)
+	if user.Status != model.StatusSubmitted || !user.SubmittedAt.Equal(at) {
+		t.Fatalf("unexpected user %+v", user)
+	}
+
+	testCases := []struct {
+		to   string
+		code int
+	}{
+		{"accepted", 400},
+		{model.StatusApproved, 409},
+		{model.StatusUnderReview, 0},
+		{model.StatusRejected, 0},
+		{model.StatusWithdrawn, 409},
+		{model.StatusUnderReview, 0},
+		{model.StatusApproved, 0},
+		{model.StatusWithdrawn, 0},
+		{model.StatusUnderReview, 409},
+	}
+	for i, tc := range testCases {
+		err := Transition(&user, tc.to, "Ada", "coordinator", " note ", at)
+		if tc.code == 0 && err != nil {
+			t.Errorf("%d: %s: unexpected error: %v", i, tc.to, err)
+		}
+		if tc.code != 0 && errors.CodeFrom(err) != tc.code {
+			t.Errorf("%d: %s: expected %d, got %v", i, tc.to, tc.code, err)
+		}
+	}
+
+	if len(user.Reviews) != 6 {
+		t.Fatalf("expected 6 reviews, got %+v", user.Reviews)
+	}
+	last := user.Reviews[5]
+	if last.From != model.StatusApproved || last.To != model.StatusWithdrawn || last.Note != "note" || last.Reviewer != "Ada" {
+		t.Errorf("unexpected review %+v", last)
+	}
+}
+
+func TestStatusOf(t *testing.T) {
+	if s := StatusOf(model.User{Enrolled: true}); s != model.StatusSubmitted {
+		t.Errorf("expected legacy applications to be submitted, got %q", s)
+	}
+	if s := StatusOf(model.User{}); s != "" {
+		t.Errorf("expected no status, got %q", s)
+	}
+}
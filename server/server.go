@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"net/http"
 	"os"
@@ -12,13 +13,19 @@ import (
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/rs/zerolog"
 
+	"github.com/Reskill-2022/volunteering/audit"
+	"github.com/Reskill-2022/volunteering/auth"
 	"github.com/Reskill-2022/volunteering/config"
 	"github.com/Reskill-2022/volunteering/controllers"
-	"github.com/Reskill-2022/volunteering/linkedin"
+	"github.com/Reskill-2022/volunteering/forms"
+	"github.com/Reskill-2022/volunteering/identity"
+	"github.com/Reskill-2022/volunteering/notify"
 	"github.com/Reskill-2022/volunteering/repository"
+	"github.com/Reskill-2022/volunteering/taxonomy"
+	"github.com/Reskill-2022/volunteering/webhooks"
 )
 
-func registerRoutes(e *echo.Echo, cts *controllers.Container, rc *repository.Container, service linkedin.Service) {
+func registerRoutes(e *echo.Echo, cts *controllers.Container, rc *repository.Container, providers identity.Providers, sessions *auth.Sessions, apiKeys *auth.APIKeys, catalog *taxonomy.Catalog, registry *forms.Registry, notifier *notify.Notifier, hooks *webhooks.Dispatcher) {
 	e.Use(middleware.Logger())
 	// allow all origins
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
@@ -28,30 +35,90 @@ func registerRoutes(e *echo.Echo, cts *controllers.Container, rc *repository.Con
 		AllowCredentials: true,
 	}))
 
+	// name the endpoint of every change in the audit log
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := audit.WithEndpoint(c.Request().Context(), c.Request().Method+" "+c.Path())
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	})
+
 	api := e.Group("/volunteering")
 
 	api.GET("/health", func(c echo.Context) error {
 		return c.String(http.StatusOK, "Backend! OK")
 	})
+	api.GET("/metrics", echo.WrapHandler(expvar.Handler()), apiKeys.Middleware(), auth.RequireRole(config.AdminViewer))
+	api.GET("/taxonomy", cts.TaxonomyController.GetTaxonomy(catalog))
+	api.GET("/taxonomy/:name", cts.TaxonomyController.GetOptionList(catalog))
+	api.GET("/form", cts.FormController.GetForm(registry, catalog))
+	api.GET("/form/:version", cts.FormController.GetForm(registry, catalog))
 	{
 		users := api.Group("/users")
 
-		users.POST("", cts.UserController.CreateUser(rc.UserRepository, service))
-		users.PUT("/:email", cts.UserController.UpdateUser(rc.UserRepository, rc.UserRepository))
-		users.GET("/:email", cts.UserController.GetUser(rc.UserRepository))
+		users.POST("", cts.UserController.CreateUser(rc.UserRepository, providers, sessions, notifier, hooks))
+		users.GET("", cts.AdminController.ListUsers(rc.UserRepository), apiKeys.Middleware(), auth.RequireRole(config.AdminViewer))
+
+		// a session may only read and update its own record
+		self := users.Group("/:email", sessions.Middleware(), auth.RequireSelf("email"))
+		self.PUT("", cts.UserController.UpdateUser(rc.UserRepository, rc.UserRepository, registry, catalog, notifier, hooks))
+		self.PATCH("/draft", cts.UserController.SaveDraft(rc.UserRepository, rc.UserRepository, registry, catalog))
+		self.POST("/submit", cts.UserController.SubmitApplication(rc.UserRepository, rc.UserRepository, registry, catalog, notifier, hooks))
+		self.POST("/withdraw", cts.UserController.Withdraw(rc.UserRepository, rc.UserRepository, hooks))
+		self.GET("", cts.UserController.GetUser(rc.UserRepository))
+	}
+	{
+		admin := api.Group("/admin", apiKeys.Middleware())
+		viewer := auth.RequireRole(config.AdminViewer)
+		coordinator := auth.RequireRole(config.AdminCoordinator)
+		administrator := auth.RequireRole(config.AdminAdmin)
+
+		users := admin.Group("/users")
+		users.GET("", cts.AdminController.ListUsers(rc.UserRepository), viewer)
+		users.GET("/:email", cts.AdminController.GetUser(rc.UserRepository), viewer)
+		users.PATCH("/:email", cts.AdminController.UpdateUser(rc.UserRepository, rc.UserRepository, catalog), coordinator)
+		users.GET("/:email/audit", cts.AdminController.GetAudit(rc.UserRepository, rc.Audit), viewer)
+		users.GET("/:email/reviews", cts.AdminController.GetReviews(rc.UserRepository), viewer)
+		users.POST("/:email/status", cts.AdminController.ReviewUser(rc.UserRepository, rc.UserRepository, notifier, hooks), coordinator)
+		users.POST("/:email/deactivate", cts.AdminController.SetDeactivated(rc.UserRepository, rc.UserRepository, true), administrator)
+		users.POST("/:email/reactivate", cts.AdminController.SetDeactivated(rc.UserRepository, rc.UserRepository, false), administrator)
+
+		admin.GET("/export", cts.AdminController.ExportUsers(rc.UserRepository), coordinator)
+		admin.PUT("/taxonomy/:name", cts.TaxonomyController.PutOptionList(catalog), administrator)
+		admin.POST("/forms", cts.FormController.CreateForm(registry), administrator)
+		admin.GET("/jobs", cts.JobController.ListJobs(rc.Jobs), administrator)
+		admin.POST("/jobs/:id/retry", cts.JobController.RetryJob(rc.Jobs), administrator)
+		admin.GET("/webhooks", cts.WebhookController.ListWebhooks(hooks), administrator)
+		admin.GET("/webhooks/deliveries", cts.WebhookController.ListDeliveries(rc.Deliveries), administrator)
+		admin.GET("/webhooks/deliveries/:id", cts.WebhookController.GetDelivery(rc.Deliveries), administrator)
+		admin.POST("/webhooks/deliveries/:id/redeliver", cts.WebhookController.Redeliver(hooks), administrator)
 	}
 }
 
-func Start(logger zerolog.Logger, env config.Environment, cts *controllers.Container, rc *repository.Container, service linkedin.Service) error {
+func Start(logger zerolog.Logger, cfg *config.Config, cts *controllers.Container, rc *repository.Container, providers identity.Providers) error {
 	e := echo.New()
 
-	registerRoutes(e, cts, rc, service)
+	sessions := auth.New([]byte(cfg.Session.Secret), cfg.Session.TTL)
+	apiKeys := auth.NewAPIKeys(cfg.Admin.APIKeys)
+	if len(cfg.Admin.APIKeys) == 0 {
+		logger.Warn().Msgf("No %s configured, the admin API is disabled", config.AdminAPIKeys)
+	}
+	notifier, err := notify.FromConfig(logger, cfg.Mail)
+	if err != nil {
+		return err
+	}
+	hooks, err := webhooks.New(logger, cfg.Webhooks, rc.Deliveries, rc.Jobs)
+	if err != nil {
+		return err
+	}
+	registerRoutes(e, cts, rc, providers, sessions, apiKeys, taxonomy.NewCatalog(rc.Taxonomy), forms.NewRegistry(rc.Forms), notifier, hooks)
 
 	srv := &http.Server{
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  10 * time.Second,
-		Addr:         fmt.Sprintf(":%s", env[config.Port]),
+		Addr:         fmt.Sprintf(":%s", cfg.Port),
 	}
 
 	// gracefully start server
@@ -74,6 +141,10 @@ func Start(logger zerolog.Logger, env config.Environment, cts *controllers.Conta
 		logger.Fatal().Err(err).Msg("Failed to shutdown server")
 	}
 
+	if err := notifier.Wait(ctx); err != nil {
+		logger.Warn().Err(err).Msg("Shut down before every notification was sent")
+	}
+
 	logger.Info().Msg("Server gracefully shutdown")
 	return nil
 }
@@ -0,0 +1,48 @@
+package taxonomy
+
+import "github.com/Reskill-2022/volunteering/model"
+
+var usStates = []string{
+	"Alabama", "Alaska", "Arizona", "Arkansas", "California", "Colorado",
+	"Connecticut", "Delaware", "District of Columbia", "Florida", "Georgia",
+	"Hawaii", "Idaho", "Illinois", "Indiana", "Iowa", "Kansas", "Kentucky",
+	"Louisiana", "Maine", "Maryland", "Massachusetts", "Michigan", "Minnesota",
+	"Mississippi", "Missouri", "Montana", "Nebraska", "Nevada", "New Hampshire",
+	"New Jersey", "New Mexico", "New York", "North Carolina", "North Dakota",
+	"Ohio", "Oklahoma", "Oregon", "Pennsylvania", "Rhode Island",
+	"South Carolina", "South Dakota", "Tennessee", "Texas", "Utah", "Vermont",
+	"Virginia", "Washington", "West Virginia", "Wisconsin", "Wyoming",
+	"Outside the US",
+}
+
+// Defaults returns the built-in lists.
+func Defaults() Taxonomy {
+	return Taxonomy{
+		States: same(usStates...),
+		Experience: {
+			{Value: "0-1", Label: "Less than a year"},
+			{Value: "1-5", Label: "1 to 5 years"},
+			{Value: "5-10", Label: "5 to 10 years"},
+			{Value: "10-20", Label: "10 to 20 years"},
+			{Value: "20+", Label: "More than 20 years"},
+		},
+		Areas: same(
+			"Mentoring", "Career Coaching", "Resume Review", "Mock Interviews",
+			"Technical Training", "Guest Speaking", "Job Referrals", "Program Design",
+		),
+		Means: same("Remote", "In Person", "Hybrid"),
+		Representation: {
+			{Value: "Self", Label: "Myself"},
+			{Value: "Organization", Label: "My organization"},
+		},
+	}
+}
+
+// same returns options whose labels are their values.
+func same(values ...string) []model.Option {
+	options := make([]model.Option, len(values))
+	for i, v := range values {
+		options[i] = model.Option{Value: v, Label: v}
+	}
+	return options
+}
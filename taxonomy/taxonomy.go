@@ -0,0 +1,164 @@
+// Package taxonomy holds the option lists that application answers are
+// checked against, such as the US states and volunteer areas. Admins can
+// replace any list; lists that were never saved use Defaults.
+package taxonomy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/model"
+	"github.com/Reskill-2022/volunteering/repository"
+)
+
+// List names.
+const (
+	States         = "states"
+	Experience     = "experience"
+	Areas          = "volunteer_areas"
+	Means          = "volunteer_means"
+	Representation = "representation"
+)
+
+// Names lists every option list.
+var Names = []string{States, Experience, Areas, Means, Representation}
+
+// cacheTTL bounds how long lists saved by another instance take to apply.
+const cacheTTL = time.Minute
+
+// Taxonomy maps list names to their options.
+type Taxonomy map[string][]model.Option
+
+// Allows reports whether value is an option of list.
+func (t Taxonomy) Allows(list, value string) bool {
+	for _, o := range t[list] {
+		if o.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns a 400 error naming the first of values that is not an option
+// of list. field is the question as shown to volunteers.
+func (t Taxonomy) Check(list, field string, values ...string) error {
+	for _, v := range values {
+		if !t.Allows(list, v) {
+			return errors.New(fmt.Sprintf("Invalid %s '%s'. Choose one of the listed options", field, v), 400)
+		}
+	}
+	return nil
+}
+
+// CheckUser checks the application answers of user against the lists.
+// Unanswered questions are left to the callers' required field checks.
+func (t Taxonomy) CheckUser(user model.User) error {
+	checks := []struct {
+		list, field string
+		values      []string
+	}{
+		{States, "State", nonEmpty(user.State)},
+		{Experience, "Years of Experience", nonEmpty(user.YearsOfExperience)},
+		{Areas, "Volunteer Area", user.VolunteerAreas},
+		{Means, "Volunteer Means", user.VolunteerMeans},
+		{Representation, "Representation", nonEmpty(user.Representation)},
+	}
+	for _, c := range checks {
+		if err := t.Check(c.list, c.field, c.values...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func nonEmpty(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return []string{value}
+}
+
+// ValidateList checks that a list can be saved.
+func ValidateList(name string, options []model.Option) error {
+	known := false
+	for _, n := range Names {
+		known = known || n == name
+	}
+	if !known {
+		return errors.New(fmt.Sprintf("Unknown Option List '%s'. Use one of: %s", name, strings.Join(Names, ", ")), 404)
+	}
+	if len(options) == 0 {
+		return errors.New("An Option List needs at least one option", 400)
+	}
+
+	seen := make(map[string]bool)
+	for _, o := range options {
+		if strings.TrimSpace(o.Value) == "" || strings.TrimSpace(o.Label) == "" {
+			return errors.New("Every option needs a value and a label", 400)
+		}
+		if strings.Contains(o.Value, ",") {
+			// older clients send multi-valued answers comma-separated
+			return errors.New(fmt.Sprintf("Option '%s' must not contain a comma", o.Value), 400)
+		}
+		if seen[o.Value] {
+			return errors.New(fmt.Sprintf("Option '%s' is listed more than once", o.Value), 400)
+		}
+		seen[o.Value] = true
+	}
+	return nil
+}
+
+// Catalog serves the taxonomy from a repository.TaxonomyStore, caching it
+// for up to a minute.
+type Catalog struct {
+	store repository.TaxonomyStore
+
+	mu       sync.Mutex
+	cached   Taxonomy
+	loadedAt time.Time
+}
+
+func NewCatalog(store repository.TaxonomyStore) *Catalog {
+	return &Catalog{store: store}
+}
+
+// Get returns every list, falling back to Defaults for lists never saved.
+func (c *Catalog) Get(ctx context.Context) (Taxonomy, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cached != nil && time.Since(c.loadedAt) < cacheTTL {
+		return c.cached, nil
+	}
+
+	saved, err := c.store.GetOptionLists(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	t := Defaults()
+	for name, options := range saved {
+		t[name] = options
+	}
+	c.cached, c.loadedAt = t, time.Now()
+	return t, nil
+}
+
+// Put validates and saves a list, replacing its options.
+func (c *Catalog) Put(ctx context.Context, name string, options []model.Option) error {
+	if err := ValidateList(name, options); err != nil {
+		return err
+	}
+	if err := c.store.SaveOptionList(ctx, name, options); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.cached = nil
+	c.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,104 @@
+package taxonomy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/model"
+	"github.com/Reskill-2022/volunteering/repository"
+)
+
+func TestCatalog(t *testing.T) {
+	ctx := context.Background()
+	catalog := NewCatalog(repository.NewMemoryUserRepository(zerolog.Nop()))
+
+	lists, err := catalog.Get(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(lists) != len(Names) || len(lists[States]) != 52 {
+		t.Fatalf("expected the default lists, got %d lists and %d states", len(lists), len(lists[States]))
+	}
+
+	means := []model.Option{{Value: "Remote", Label: "Remote"}, {Value: "Weekends", Label: "On weekends"}}
+	if err := catalog.Put(ctx, Means, means); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lists, err = catalog.Get(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !lists.Allows(Means, "Weekends") || lists.Allows(Means, "Hybrid") {
+		t.Errorf("expected the saved means to replace the defaults, got %+v", lists[Means])
+	}
+	if !lists.Allows(States, "Texas") {
+		t.Errorf("expected the default states to remain")
+	}
+}
+
+func TestValidateList(t *testing.T) {
+	testCases := []struct {
+		name    string
+		list    string
+		options []model.Option
+		code    int
+	}{
+		{"valid", Areas, []model.Option{{Value: "Mentoring", Label: "Mentoring"}}, 0},
+		{"unknown list", "colors", []model.Option{{Value: "Red", Label: "Red"}}, 404},
+		{"empty", Areas, nil, 400},
+		{"missing label", Areas, []model.Option{{Value: "Mentoring"}}, 400},
+		{"comma", Areas, []model.Option{{Value: "Resume, Review", Label: "Resume Review"}}, 400},
+		{"duplicate", Areas, []model.Option{{Value: "A", Label: "A"}, {Value: "A", Label: "Also A"}}, 400},
+	}
+
+	for _, tc := range testCases {
+		err := ValidateList(tc.list, tc.options)
+		if tc.code == 0 && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+		if tc.code != 0 && errors.CodeFrom(err) != tc.code {
+			t.Errorf("%s: expected %d, got %v", tc.name, tc.code, err)
+		}
+	}
+}
+
+func TestCheckUser(t *testing.T) {
+	valid := model.User{
+		State:             "New York",
+		YearsOfExperience: "5-10",
+		VolunteerAreas:    model.StringList{"Mentoring", "Resume Review"},
+		VolunteerMeans:    model.StringList{"Remote"},
+		Representation:    "Self",
+	}
+
+	testCases := []struct {
+		name   string
+		modify func(u *model.User)
+		err    string
+	}{
+		{"valid", func(u *model.User) {}, ""},
+		{"unanswered", func(u *model.User) { *u = model.User{} }, ""},
+		{"abbreviated state", func(u *model.User) { u.State = "NY" }, "Invalid State 'NY'. Choose one of the listed options"},
+		{"case", func(u *model.User) { u.VolunteerMeans = model.StringList{"remote"} }, "Invalid Volunteer Means 'remote'. Choose one of the listed options"},
+		{"second area", func(u *model.User) { u.VolunteerAreas = append(u.VolunteerAreas, "Cooking") }, "Invalid Volunteer Area 'Cooking'. Choose one of the listed options"},
+		{"experience", func(u *model.User) { u.YearsOfExperience = "7" }, "Invalid Years of Experience '7'. Choose one of the listed options"},
+	}
+
+	for _, tc := range testCases {
+		user := valid
+		user.VolunteerAreas = append(model.StringList(nil), valid.VolunteerAreas...)
+		tc.modify(&user)
+
+		err := Defaults().CheckUser(user)
+		if tc.err == "" && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+		if tc.err != "" && (err == nil || err.(errors.Error).Message() != tc.err) {
+			t.Errorf("%s: expected %q, got %v", tc.name, tc.err, err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Package validation checks the free-text answers that cannot be offered as
+// options: LinkedIn profile URLs and industries.
+package validation
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/Reskill-2022/volunteering/errors"
+)
+
+// MaxIndustries is the most industries a volunteer can list.
+const MaxIndustries = 10
+
+// maxIndustryLength bounds a single industry.
+const maxIndustryLength = 100
+
+var (
+	errLinkedInURL = errors.New("Invalid LinkedIn Profile URL. Use the link to your profile, like https://www.linkedin.com/in/your-name", 400)
+
+	// linkedInHost matches linkedin.com and its www, mobile and locale
+	// subdomains, such as uk.linkedin.com or de.linkedin.com.
+	linkedInHost = regexp.MustCompile(`^(?:(?:www|m|[a-z]{2})\.)?linkedin\.com$`)
+
+	// profileSlug is the public profile name LinkedIn allows: letters,
+	// digits, hyphens and, for older profiles, underscores, or their
+	// percent-encoded non-Latin equivalents.
+	profileSlug = regexp.MustCompile(`^[\p{L}\p{N}_-]{3,100}$`)
+)
+
+// LinkedInURL checks that raw links to a LinkedIn member profile and returns
+// it as https://www.linkedin.com/in/<name>. The scheme may be left out, and
+// the mobile ("mwlite", m.) and locale subdomain forms are accepted. Query
+// strings, fragments and trailing paths such as /details are dropped.
+func LinkedInURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", errLinkedInURL
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return "", errLinkedInURL
+	}
+	if !linkedInHost.MatchString(strings.ToLower(u.Hostname())) {
+		return "", errLinkedInURL
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segments) > 0 && segments[0] == "mwlite" {
+		segments = segments[1:]
+	}
+	if len(segments) < 2 || segments[0] != "in" || !profileSlug.MatchString(segments[1]) {
+		return "", errLinkedInURL
+	}
+
+	return "https://www.linkedin.com/in/" + strings.ToLower(segments[1]), nil
+}
+
+// ParseIndustries splits a comma-separated list of industries and checks it
+// like Industries.
+func ParseIndustries(raw string) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, errors.New("Missing Field! Industries is required", 400)
+	}
+	industries := strings.Split(raw, ",")
+	for i := range industries {
+		industries[i] = strings.TrimSpace(industries[i])
+	}
+	return industries, Industries(industries)
+}
+
+// Industries checks that there are between one and MaxIndustries industries
+// and that none is blank or overly long.
+func Industries(industries []string) error {
+	if len(industries) == 0 {
+		return errors.New("Missing Field! Industries is required", 400)
+	}
+	if len(industries) > MaxIndustries {
+		return errors.New(fmt.Sprintf("Too Many Industries. List at most %d", MaxIndustries), 400)
+	}
+	for _, industry := range industries {
+		industry = strings.TrimSpace(industry)
+		if industry == "" {
+			return errors.New("Invalid Industries. Separate industries with single commas", 400)
+		}
+		if runes := []rune(industry); len(runes) > maxIndustryLength {
+			return errors.New(fmt.Sprintf("Invalid Industry '%s...'. Industries must be at most %d characters", string(runes[:20]), maxIndustryLength), 400)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/Reskill-2022/volunteering/errors"
+)
+
+func TestLinkedInURL(t *testing.T) {
+	testCases := []struct {
+		url  string
+		want string
+	}{
+		{"https://www.linkedin.com/in/james-bond-007/", "https://www.linkedin.com/in/james-bond-007"},
+		{"linkedin.com/in/Marllos-P-a383641b2", "https://www.linkedin.com/in/marllos-p-a383641b2"},
+		{"https://www.linkedin.com/mwlite/in/techypally", "https://www.linkedin.com/in/techypally"},
+		{"http://uk.linkedin.com/in/jane_doe?trk=profile", "https://www.linkedin.com/in/jane_doe"},
+		{"https://m.linkedin.com/in/jane-doe/details/experience/", "https://www.linkedin.com/in/jane-doe"},
+		{"https://de.linkedin.com/in/j%C3%BCrgen-m%C3%BCller", "https://www.linkedin.com/in/jürgen-müller"},
+		{"https://", ""},
+		{"https://www.linkedin.com/in/", ""},
+		{"https://www.linkedin.com/company/reskill", ""},
+		{"https://linkedin.com.evil.example/in/jane", ""},
+		{"https://github.com/in/jane", ""},
+		{"ftp://linkedin.com/in/jane", ""},
+	}
+
+	for _, tc := range testCases {
+		got, err := LinkedInURL(tc.url)
+		if tc.want == "" {
+			if err == nil {
+				t.Errorf("LinkedInURL(%s) = %s, want error", tc.url, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("LinkedInURL(%s) = %s, %v, want %s", tc.url, got, err, tc.want)
+		}
+	}
+}
+
+func TestParseIndustries(t *testing.T) {
+	got, err := ParseIndustries(" Finance , Health Care")
+	if err != nil || len(got) != 2 || got[1] != "Health Care" {
+		t.Errorf("unexpected industries %q, %v", got, err)
+	}
+
+	if _, err := ParseIndustries("a,b,c,d,e,f,g,h,i,j,k"); err == nil {
+		t.Errorf("expected an error for more than %d industries", MaxIndustries)
+	}
+
+	// long names are cut by character, not byte
+	_, err = ParseIndustries("é" + strings.Repeat("ü", maxIndustryLength))
+	if e, ok := err.(errors.Error); !ok || !utf8.ValidString(e.Message()) || !strings.Contains(e.Message(), "'é"+strings.Repeat("ü", 19)+"...'") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
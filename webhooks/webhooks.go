@@ -0,0 +1,343 @@
+// Package webhooks notifies partner systems of volunteer events. Each event
+// is recorded as a delivery per subscribed webhook and posted by a background
+// job, signed with the webhook's secret:
+//
+//	X-Webhook-Signature: sha256=hex(HMAC-SHA256(secret, timestamp + "." + body))
+//
+// where timestamp is the X-Webhook-Timestamp header, in Unix seconds.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Reskill-2022/volunteering/config"
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/jobs"
+	"github.com/Reskill-2022/volunteering/model"
+	"github.com/Reskill-2022/volunteering/repository"
+	"github.com/Reskill-2022/volunteering/review"
+)
+
+// Event types.
+const (
+	VolunteerCreated = "volunteer.created"
+	// VolunteerEnrolled is sent when a volunteer submits their application.
+	VolunteerEnrolled = "volunteer.enrolled"
+	StatusChanged     = "application.status_changed"
+)
+
+// Events lists every event type.
+var Events = []string{VolunteerCreated, VolunteerEnrolled, StatusChanged}
+
+// Request headers.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const jobDeliver = "webhook_delivery"
+
+// requestTimeout bounds each delivery attempt.
+const requestTimeout = 10 * time.Second
+
+// Event is the body of every delivery.
+type Event struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Webhook describes a subscription, without its secret.
+type Webhook struct {
+	Name   string   `json:"name"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+type deliverJob struct {
+	Delivery string `json:"delivery"`
+}
+
+// Dispatcher records and delivers events to the configured webhooks. A nil
+// Dispatcher delivers nothing.
+type Dispatcher struct {
+	logger   zerolog.Logger
+	webhooks []config.Webhook
+	store    repository.DeliveryStore
+	queue    *jobs.Queue
+	client   *http.Client
+}
+
+// New returns a Dispatcher for webhooks and registers its deliveries with
+// queue.
+func New(logger zerolog.Logger, webhooks []config.Webhook, store repository.DeliveryStore, queue *jobs.Queue) (*Dispatcher, error) {
+	for _, w := range webhooks {
+		for _, event := range w.Events {
+			if !known(event) {
+				return nil, fmt.Errorf("webhook %s: unknown event '%s'", w.Name, event)
+			}
+		}
+	}
+
+	d := &Dispatcher{
+		logger:   logger,
+		webhooks: webhooks,
+		store:    store,
+		queue:    queue,
+		client:   &http.Client{Timeout: requestTimeout},
+	}
+	queue.Register(jobDeliver, d.deliver)
+	return d, nil
+}
+
+func known(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhooks returns the subscriptions.
+func (d *Dispatcher) Webhooks() []Webhook {
+	webhooks := []Webhook{}
+	if d == nil {
+		return webhooks
+	}
+	for _, w := range d.webhooks {
+		events := w.Events
+		if len(events) == 0 {
+			events = Events
+		}
+		webhooks = append(webhooks, Webhook{Name: w.Name, URL: w.URL, Events: events})
+	}
+	return webhooks
+}
+
+// Emit queues a delivery of the event to every webhook subscribed to it.
+// Failures are logged; they never fail the change that caused the event.
+func (d *Dispatcher) Emit(ctx context.Context, eventType string, data interface{}) {
+	if d == nil {
+		return
+	}
+
+	now := time.Now().UTC()
+	event := Event{ID: newID(now), Type: eventType, CreatedAt: now, Data: data}
+	var payload []byte
+
+	for _, w := range d.webhooks {
+		if !subscribed(w, eventType) {
+			continue
+		}
+		if payload == nil {
+			var err error
+			if payload, err = json.Marshal(event); err != nil {
+				d.logger.Err(err).Msgf("Webhooks: failed to encode %s", eventType)
+				return
+			}
+		}
+
+		delivery := model.Delivery{
+			ID:        newID(now),
+			Webhook:   w.Name,
+			Event:     eventType,
+			Payload:   payload,
+			Status:    model.DeliveryPending,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if err := d.schedule(ctx, delivery); err != nil {
+			d.logger.Err(err).Msgf("Webhooks: failed to queue %s for %s", eventType, w.Name)
+		}
+	}
+}
+
+func subscribed(w config.Webhook, eventType string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Dispatcher) schedule(ctx context.Context, delivery model.Delivery) error {
+	if err := d.store.SaveDelivery(ctx, delivery); err != nil {
+		return err
+	}
+	_, err := d.queue.Enqueue(jobDeliver, deliverJob{Delivery: delivery.ID})
+	return err
+}
+
+// Redeliver sends delivery id again, whether or not it was delivered.
+func (d *Dispatcher) Redeliver(ctx context.Context, id string) (*model.Delivery, error) {
+	delivery, err := d.store.GetDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := d.webhook(delivery.Webhook); !ok {
+		return nil, errors.New(fmt.Sprintf("Webhook '%s' Is No Longer Configured", delivery.Webhook), 409)
+	}
+
+	delivery.Status = model.DeliveryPending
+	delivery.UpdatedAt = time.Now().UTC()
+	if err := d.schedule(ctx, *delivery); err != nil {
+		return nil, errors.From(err, "failed to queue redelivery", 500)
+	}
+	return delivery, nil
+}
+
+func (d *Dispatcher) webhook(name string) (config.Webhook, bool) {
+	for _, w := range d.webhooks {
+		if w.Name == name {
+			return w, true
+		}
+	}
+	return config.Webhook{}, false
+}
+
+// deliver posts one delivery. A failed attempt returns an error, so that the
+// job is retried.
+func (d *Dispatcher) deliver(ctx context.Context, raw json.RawMessage) error {
+	var job deliverJob
+	if err := json.Unmarshal(raw, &job); err != nil {
+		return err
+	}
+	delivery, err := d.store.GetDelivery(ctx, job.Delivery)
+	if err != nil {
+		return err
+	}
+	if delivery.Status == model.DeliveryDelivered {
+		return nil
+	}
+	w, ok := d.webhook(delivery.Webhook)
+	if !ok {
+		d.logger.Warn().Msgf("Webhooks: dropping delivery %s to removed webhook %s", delivery.ID, delivery.Webhook)
+		return nil
+	}
+
+	code, err := d.post(ctx, w, *delivery)
+
+	now := time.Now().UTC()
+	delivery.Attempts++
+	delivery.ResponseCode = code
+	delivery.UpdatedAt = now
+	if err == nil {
+		delivery.Status = model.DeliveryDelivered
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+	} else {
+		delivery.Status = model.DeliveryFailed
+		delivery.LastError = err.Error()
+	}
+	if err := d.store.SaveDelivery(ctx, *delivery); err != nil {
+		d.logger.Err(err).Msgf("Webhooks: failed to record delivery %s", delivery.ID)
+	}
+	return err
+}
+
+func (d *Dispatcher) post(ctx context.Context, w config.Webhook, delivery model.Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build request: %w", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(w.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to post to %s: %w", w.Name, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("%s responded %d", w.Name, resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the X-Webhook-Signature of body sent at timestamp.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the signature of body sent at
+// timestamp, for receivers written in Go.
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// newID returns an ID that sorts after those created before at.
+func newID(at time.Time) string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%016x%s", at.UnixNano(), hex.EncodeToString(b))
+}
+
+// Volunteer is the volunteer as sent to webhooks. Only these fields are
+// shared with partners; answers and contact details stay here.
+type Volunteer struct {
+	Email       string     `json:"email"`
+	Name        string     `json:"name"`
+	FirstName   string     `json:"first_name"`
+	LastName    string     `json:"last_name"`
+	Provider    string     `json:"provider"`
+	Status      string     `json:"status,omitempty"`
+	SubmittedAt *time.Time `json:"submitted_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// VolunteerData is the data of volunteer.created and volunteer.enrolled
+// events.
+func VolunteerData(user model.User) map[string]interface{} {
+	return map[string]interface{}{"volunteer": Volunteer{
+		Email:       user.Email,
+		Name:        user.Name,
+		FirstName:   user.FirstName,
+		LastName:    user.LastName,
+		Provider:    user.Provider,
+		Status:      review.StatusOf(user),
+		SubmittedAt: user.SubmittedAt,
+		CreatedAt:   user.CreatedAt,
+	}}
+}
+
+// StatusChangeData is the data of an application.status_changed event, for
+// user's latest review. Reviewers' notes are left out.
+func StatusChangeData(user model.User) map[string]interface{} {
+	data := VolunteerData(user)
+	if n := len(user.Reviews); n > 0 {
+		last := user.Reviews[n-1]
+		data["from"], data["to"], data["changed_by"] = last.From, last.To, last.Role
+	}
+	return data
+}
@@ -0,0 +1,196 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Reskill-2022/volunteering/config"
+	"github.com/Reskill-2022/volunteering/errors"
+	"github.com/Reskill-2022/volunteering/jobs"
+	"github.com/Reskill-2022/volunteering/model"
+	"github.com/Reskill-2022/volunteering/repository"
+)
+
+const secret = "0123456789abcdef"
+
+// receiver records signed requests, failing the first failures of them.
+type receiver struct {
+	t        *testing.T
+	mu       sync.Mutex
+	failures int
+	events   []string
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	if !Verify(secret, req.Header.Get(HeaderTimestamp), body, req.Header.Get(HeaderSignature)) {
+		r.t.Errorf("bad signature for delivery %s", req.Header.Get(HeaderDelivery))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	r.events = append(r.events, req.Header.Get(HeaderEvent))
+}
+
+func (r *receiver) received() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.events)
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	recv := &receiver{t: t, failures: 1}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	queue, err := jobs.Open(zerolog.Nop(), filepath.Join(t.TempDir(), "jobs.db"), jobs.Options{RetryInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("failed to open queue: %v", err)
+	}
+	defer queue.Close()
+
+	store := repository.NewMemoryUserRepository(zerolog.Nop())
+	d, err := New(zerolog.Nop(), []config.Webhook{
+		{Name: "crm", URL: server.URL, Events: []string{VolunteerCreated}, Secret: secret},
+		{Name: "all", URL: server.URL, Secret: secret},
+	}, store, queue)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go queue.Run(ctx)
+
+	d.Emit(ctx, VolunteerCreated, VolunteerData(model.User{Email: "a@example.com"}))
+	d.Emit(ctx, StatusChanged, map[string]interface{}{"from": model.StatusSubmitted, "to": model.StatusApproved})
+	waitFor(t, "deliveries", func() bool { return recv.received() == 3 })
+
+	waitFor(t, "delivery log", func() bool {
+		deliveries, err := store.ListDeliveries(ctx, "", 10)
+		if err != nil {
+			t.Fatalf("ListDeliveries: %v", err)
+		}
+		for _, delivery := range deliveries {
+			if delivery.Status != model.DeliveryDelivered {
+				return false
+			}
+		}
+		return len(deliveries) == 3
+	})
+
+	deliveries, _ := store.ListDeliveries(ctx, "crm", 10)
+	if len(deliveries) != 1 || deliveries[0].Event != VolunteerCreated {
+		t.Fatalf("crm deliveries = %+v, want one %s", deliveries, VolunteerCreated)
+	}
+	all, _ := store.ListDeliveries(ctx, "all", 10)
+	if len(all) != 2 || all[0].Event != StatusChanged {
+		t.Fatalf("all deliveries = %+v, want two, newest first", all)
+	}
+	attempts := all[0].Attempts + all[1].Attempts + deliveries[0].Attempts
+	if attempts != 4 {
+		t.Errorf("attempts = %d, want 4 with one retry", attempts)
+	}
+
+	if _, err := d.Redeliver(ctx, deliveries[0].ID); err != nil {
+		t.Fatalf("Redeliver: %v", err)
+	}
+	waitFor(t, "redelivery", func() bool { return recv.received() == 4 })
+
+	if _, err := d.Redeliver(ctx, "missing"); errors.CodeFrom(err) != 404 {
+		t.Errorf("Redeliver(missing) = %v, want 404", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	queue, err := jobs.Open(zerolog.Nop(), filepath.Join(t.TempDir(), "jobs.db"), jobs.Options{})
+	if err != nil {
+		t.Fatalf("failed to open queue: %v", err)
+	}
+	defer queue.Close()
+
+	store := repository.NewMemoryUserRepository(zerolog.Nop())
+	if _, err := New(zerolog.Nop(), []config.Webhook{{Name: "crm", Events: []string{"volunteer.deleted"}}}, store, queue); err == nil {
+		t.Error("New accepted an unknown event")
+	}
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"type":"volunteer.created"}`)
+	signature := Sign(secret, "1700000000", body)
+	if !Verify(secret, "1700000000", body, signature) {
+		t.Error("Verify rejected its own signature")
+	}
+	if Verify(secret, "1700000001", body, signature) {
+		t.Error("Verify accepted a signature for another timestamp")
+	}
+	if Verify("another secret!!", "1700000000", body, signature) {
+		t.Error("Verify accepted a signature made with another secret")
+	}
+}
+
+func TestVolunteerData(t *testing.T) {
+	user := model.User{
+		Email:     "jane@example.com",
+		Name:      "Jane Doe",
+		Phone:     "+1 512 555 0100",
+		Convicted: true,
+		Enrolled:  true,
+		Answers:   map[string]interface{}{"convicted": true},
+		Reviews:   []model.Review{{From: model.StatusSubmitted, To: model.StatusRejected, Note: "internal", Role: "coordinator"}},
+	}
+
+	raw, err := json.Marshal(StatusChangeData(user))
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	var data struct {
+		Volunteer map[string]interface{} `json:"volunteer"`
+		To        string                 `json:"to"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+
+	var keys []string
+	for key := range data.Volunteer {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	want := []string{"created_at", "email", "first_name", "last_name", "name", "provider", "status"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("volunteer fields = %v, want %v", keys, want)
+	}
+	if data.To != model.StatusRejected || strings.Contains(string(raw), "internal") {
+		t.Errorf("unexpected data %s", raw)
+	}
+}